@@ -0,0 +1,24 @@
+package db
+
+import "bytes"
+
+// BatchValues returns the rows of placeholders for a
+// multi-row INSERT, such as (?,?),(?,?), or ($1,$2),($3,$4)
+// with numbered placeholders.
+func BatchValues(rows, cols int, d Dialect) string {
+	var buf bytes.Buffer
+	for i := 0; i < rows; i++ {
+		if i != 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("(")
+		for j := 0; j < cols; j++ {
+			if j != 0 {
+				buf.WriteString(",")
+			}
+			buf.WriteString(d.Param(i*cols + j))
+		}
+		buf.WriteString(")")
+	}
+	return buf.String()
+}
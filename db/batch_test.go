@@ -0,0 +1,23 @@
+package db
+
+import "testing"
+
+var batchTests = []struct {
+	rows, cols int
+	dialect    Dialect
+	want       string
+}{
+	{1, 1, SQLite, "(?)"},
+	{2, 3, MySQL, "(?,?,?),(?,?,?)"},
+	{2, 2, Postgres, "($1,$2),($3,$4)"},
+	{0, 2, Postgres, ""},
+}
+
+func TestBatchValues(t *testing.T) {
+	for _, test := range batchTests {
+		got := BatchValues(test.rows, test.cols, test.dialect)
+		if got != test.want {
+			t.Errorf("Wanted values %q, got %q", test.want, got)
+		}
+	}
+}
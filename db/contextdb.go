@@ -0,0 +1,16 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+// ContextDB is the context-aware counterpart of SimpleDB,
+// satisfied by *sql.DB, *sql.Tx and *sql.Conn. It is used
+// by the generated ...Ctx functions so that queries may be
+// cancelled or given deadlines.
+type ContextDB interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
@@ -0,0 +1,41 @@
+package db
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// ErrInvalidCursor is returned when a cursor cannot be
+// decoded into the key of the table it is used with.
+var ErrInvalidCursor = errors.New("db: invalid cursor")
+
+// EncodeCursor returns an opaque cursor holding the key
+// values of the last row of a page, used by the generated
+// ...After functions to select the following page.
+func EncodeCursor(keys ...interface{}) (string, error) {
+	raw, err := json.Marshal(keys)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// DecodeCursor decodes the key values of a cursor returned
+// by EncodeCursor into dest, one pointer for each key.
+func DecodeCursor(cursor string, dest ...interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+	var keys []json.RawMessage
+	if err := json.Unmarshal(raw, &keys); err != nil || len(keys) != len(dest) {
+		return ErrInvalidCursor
+	}
+	for i, key := range keys {
+		if err := json.Unmarshal(key, dest[i]); err != nil {
+			return ErrInvalidCursor
+		}
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package db
+
+import "testing"
+
+func TestCursor(t *testing.T) {
+	cursor, err := EncodeCursor(int64(42), "octocat")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var id int64
+	var login string
+	if err := DecodeCursor(cursor, &id, &login); err != nil {
+		t.Errorf("Wanted cursor decoded, got %s", err)
+	}
+	if id != 42 || login != "octocat" {
+		t.Errorf("Wanted keys 42 and octocat, got %d and %s", id, login)
+	}
+
+	if err := DecodeCursor(cursor, &id); err != ErrInvalidCursor {
+		t.Errorf("Wanted invalid cursor for missing key, got %v", err)
+	}
+	if err := DecodeCursor("!", &id); err != ErrInvalidCursor {
+		t.Errorf("Wanted invalid cursor for bad encoding, got %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+package db
+
+import "strconv"
+
+// Dialect is the SQL dialect of the statements built at
+// run time, such as by the query builders.
+type Dialect interface {
+	// Param returns the placeholder of the i-th parameter
+	// of a statement, counting from zero.
+	Param(i int) string
+
+	// Quote returns the name quoted as an identifier.
+	Quote(name string) string
+
+	// NullsFirst returns true if NULL sorts before any
+	// other value in ascending order.
+	NullsFirst() bool
+}
+
+// The dialects of the supported databases.
+var (
+	SQLite   Dialect = sqlite{}
+	Postgres Dialect = postgres{}
+	MySQL    Dialect = mysql{}
+)
+
+type sqlite struct{}
+
+func (sqlite) Param(int) string { return "?" }
+
+func (sqlite) Quote(name string) string { return `"` + name + `"` }
+
+func (sqlite) NullsFirst() bool { return true }
+
+type postgres struct{}
+
+func (postgres) Param(i int) string { return "$" + strconv.Itoa(i+1) }
+
+func (postgres) Quote(name string) string { return `"` + name + `"` }
+
+func (postgres) NullsFirst() bool { return false }
+
+type mysql struct{}
+
+func (mysql) Param(int) string { return "?" }
+
+func (mysql) Quote(name string) string { return "`" + name + "`" }
+
+func (mysql) NullsFirst() bool { return true }
@@ -0,0 +1,207 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ErrNotFound is returned in place of sql.ErrNoRows when
+// no row matches a generated get function. It unwraps to
+// sql.ErrNoRows, which errors.Is therefore also matches.
+var ErrNotFound error = notFound{}
+
+type notFound struct{}
+
+func (notFound) Error() string { return "db: row not found" }
+
+func (notFound) Unwrap() error { return sql.ErrNoRows }
+
+// ErrUniqueViolation is returned when a row would have the
+// same primary key or unique index as another row. The index
+// is the name reported by the database, which for sqlite is
+// the list of the index's columns.
+type ErrUniqueViolation struct {
+	Index string
+}
+
+func (e *ErrUniqueViolation) Error() string {
+	return fmt.Sprintf("db: unique index %s violated", e.Index)
+}
+
+// ErrForeignKeyViolation is returned when a row would refer
+// to a missing row, or a row still referred to is deleted.
+// The constraint is empty for sqlite, which does not report it.
+type ErrForeignKeyViolation struct {
+	Constraint string
+}
+
+func (e *ErrForeignKeyViolation) Error() string {
+	if e.Constraint == "" {
+		return "db: foreign key violated"
+	}
+	return fmt.Sprintf("db: foreign key %s violated", e.Constraint)
+}
+
+// Error is an error returned by a generated function,
+// naming the function and its table.
+type Error struct {
+	Table string
+	Func  string
+	Err   error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s %s: %v", e.Func, e.Table, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// WrapError replaces a non-nil *err with an *Error of the
+// table's function, after translating it with translate. It is
+// deferred by the generated functions.
+func WrapError(err *error, table, fn string, translate func(error) error) {
+	if *err != nil {
+		*err = &Error{Table: table, Func: fn, Err: translate(*err)}
+	}
+}
+
+// SQLiteError translates sql.ErrNoRows and the constraint
+// errors of the sqlite drivers, by their extended result codes,
+// and returns other errors unchanged.
+func SQLiteError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	code, ok := driverCode(err, "ExtendedCode", "Code")
+	if !ok {
+		return err
+	}
+	switch code {
+	case 1555, 2067: // SQLITE_CONSTRAINT_PRIMARYKEY, SQLITE_CONSTRAINT_UNIQUE
+		// UNIQUE constraint failed: users.f_login
+		msg := err.Error()
+		return &ErrUniqueViolation{Index: msg[strings.LastIndex(msg, ": ")+2:]}
+	case 787: // SQLITE_CONSTRAINT_FOREIGNKEY
+		return &ErrForeignKeyViolation{}
+	}
+	return err
+}
+
+// MySQLError translates sql.ErrNoRows and the constraint
+// errors of the mysql driver, by their error numbers, and
+// returns other errors unchanged.
+func MySQLError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	code, ok := driverCode(err, "Number")
+	if !ok {
+		return err
+	}
+	switch code {
+	case 1062: // ER_DUP_ENTRY
+		// Duplicate entry 'x' for key 'users.user_login'
+		name := quoted(err.Error(), "for key '", "'")
+		return &ErrUniqueViolation{Index: name[strings.LastIndex(name, ".")+1:]}
+	case 1216, 1217, 1451, 1452: // ER_NO_REFERENCED_ROW, ER_ROW_IS_REFERENCED
+		return &ErrForeignKeyViolation{Constraint: quoted(err.Error(), "CONSTRAINT `", "`")}
+	}
+	return err
+}
+
+// PostgresError translates sql.ErrNoRows and the constraint
+// errors of the postgres drivers, by their SQLSTATE, and
+// returns other errors unchanged. The constraint is named by
+// the driver's error, Constraint in lib/pq and ConstraintName
+// in pgx, or else by the message of an English server.
+func PostgresError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	var state interface{ SQLState() string }
+	if !errors.As(err, &state) {
+		return err
+	}
+	name, ok := driverString(err, "Constraint", "ConstraintName")
+	if !ok {
+		// duplicate key value violates unique constraint "users_pkey"
+		name = quoted(err.Error(), `constraint "`, `"`)
+	}
+	switch state.SQLState() {
+	case "23505":
+		return &ErrUniqueViolation{Index: name}
+	case "23503":
+		return &ErrForeignKeyViolation{Constraint: name}
+	}
+	return err
+}
+
+// driverCode returns the integer field or method of the
+// first error in the chain that has one of the names, as the
+// drivers' error types are not imported.
+func driverCode(err error, names ...string) (int64, bool) {
+	for ; err != nil; err = errors.Unwrap(err) {
+		v := reflect.ValueOf(err)
+		for _, name := range names {
+			if m := v.MethodByName(name); m.IsValid() && m.Type().NumIn() == 0 && m.Type().NumOut() == 1 {
+				if code, ok := intValue(m.Call(nil)[0]); ok {
+					return code, true
+				}
+			}
+			s := reflect.Indirect(v)
+			if s.Kind() != reflect.Struct {
+				continue
+			}
+			if code, ok := intValue(s.FieldByName(name)); ok {
+				return code, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// driverString returns the non-empty string field of the
+// first error in the chain that has one of the names.
+func driverString(err error, names ...string) (string, bool) {
+	for ; err != nil; err = errors.Unwrap(err) {
+		s := reflect.Indirect(reflect.ValueOf(err))
+		if s.Kind() != reflect.Struct {
+			continue
+		}
+		for _, name := range names {
+			if f := s.FieldByName(name); f.Kind() == reflect.String && f.String() != "" {
+				return f.String(), true
+			}
+		}
+	}
+	return "", false
+}
+
+func intValue(v reflect.Value) (int64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(v.Uint()), true
+	}
+	return 0, false
+}
+
+// quoted returns the text between the prefix and the next
+// quote in the message, or an empty string.
+func quoted(msg, prefix, quote string) string {
+	i := strings.Index(msg, prefix)
+	if i == -1 {
+		return ""
+	}
+	msg = msg[i+len(prefix):]
+	if j := strings.Index(msg, quote); j != -1 {
+		return msg[:j]
+	}
+	return ""
+}
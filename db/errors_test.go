@@ -0,0 +1,89 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// sqliteError has the result codes of the mattn/go-sqlite3
+// driver's errors.
+type sqliteError struct {
+	Code         int
+	ExtendedCode int
+	msg          string
+}
+
+func (e sqliteError) Error() string { return e.msg }
+
+// mysqlError has the error number of the mysql driver's
+// errors.
+type mysqlError struct {
+	Number  uint16
+	Message string
+}
+
+func (e *mysqlError) Error() string { return fmt.Sprintf("Error %d: %s", e.Number, e.Message) }
+
+// pgError has the SQLSTATE and constraint name of the pgx
+// driver's errors, with the message of a localized server.
+type pgError struct {
+	Code           string
+	ConstraintName string
+	Message        string
+}
+
+func (e *pgError) Error() string    { return e.Message }
+func (e *pgError) SQLState() string { return e.Code }
+
+func TestTranslateError(t *testing.T) {
+	errOther := errors.New("other")
+
+	var tests = []struct {
+		translate func(error) error
+		err       error
+		want      error
+	}{
+		{SQLiteError, sql.ErrNoRows, ErrNotFound},
+		{SQLiteError, sqliteError{19, 2067, "UNIQUE constraint failed: users.f_login"}, &ErrUniqueViolation{Index: "users.f_login"}},
+		{SQLiteError, sqliteError{19, 787, "FOREIGN KEY constraint failed"}, &ErrForeignKeyViolation{}},
+		{SQLiteError, errOther, errOther},
+		{MySQLError, &mysqlError{1062, "Duplicate entry 'octocat' for key 'users.user_login'"}, &ErrUniqueViolation{Index: "user_login"}},
+		{MySQLError, &mysqlError{1452, "Cannot add or update a child row: a foreign key constraint fails (`app`.`issues`, CONSTRAINT `fk_issue_user` FOREIGN KEY (`f_user_id`) REFERENCES `users` (`f_id`))"}, &ErrForeignKeyViolation{Constraint: "fk_issue_user"}},
+		{MySQLError, sql.ErrNoRows, ErrNotFound},
+		{PostgresError, stateError("23505"), &ErrUniqueViolation{}},
+		{PostgresError, fmt.Errorf(`duplicate key value violates unique constraint "user_login": %w`, stateError("23505")), &ErrUniqueViolation{Index: "user_login"}},
+		{PostgresError, fmt.Errorf("insert: %w", &pgError{"23505", "user_login", `doppelter Schlüsselwert verletzt Unique-Constraint »user_login«`}), &ErrUniqueViolation{Index: "user_login"}},
+		{PostgresError, &pgError{"23503", "fk_issue_user", `Einfügen oder Aktualisieren in Tabelle »issues« verletzt Fremdschlüssel-Constraint »fk_issue_user«`}, &ErrForeignKeyViolation{Constraint: "fk_issue_user"}},
+		{PostgresError, stateError("23503"), &ErrForeignKeyViolation{}},
+		{PostgresError, stateError("40001"), stateError("40001")},
+	}
+
+	for _, test := range tests {
+		if got := test.translate(test.err); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Wanted %v translated to %v, got %v", test.err, test.want, got)
+		}
+	}
+}
+
+func TestWrapError(t *testing.T) {
+	var err error
+	WrapError(&err, "users", "GetUserByLogin", SQLiteError)
+	if err != nil {
+		t.Errorf("Wanted nil error left nil, got %v", err)
+	}
+
+	err = sql.ErrNoRows
+	WrapError(&err, "users", "GetUserByLogin", SQLiteError)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Wanted ErrNotFound, got %v", err)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Wanted ErrNotFound to match sql.ErrNoRows, got %v", err)
+	}
+	if want := "GetUserByLogin users: db: row not found"; err.Error() != want {
+		t.Errorf("Wanted error %q, got %q", want, err)
+	}
+}
@@ -0,0 +1,20 @@
+package db
+
+// Direction is the direction a generated finder sorts
+// its results in.
+type Direction int
+
+const (
+	Asc Direction = iota
+	Desc
+)
+
+// String returns the SQL keyword of the direction. Any
+// value other than Desc sorts in ascending order, so the
+// keyword is always one of the two.
+func (d Direction) String() string {
+	if d == Desc {
+		return "DESC"
+	}
+	return "ASC"
+}
@@ -0,0 +1,232 @@
+package db
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Op is a comparison operator of a query condition.
+type Op int
+
+const (
+	Eq Op = iota
+	Ne
+	Lt
+	Le
+	Gt
+	Ge
+	Like
+	In
+	Between
+	IsNull
+	IsNotNull
+)
+
+var opSymbols = map[Op]string{
+	Eq:   "=",
+	Ne:   "<>",
+	Lt:   "<",
+	Le:   "<=",
+	Gt:   ">",
+	Ge:   ">=",
+	Like: " LIKE ",
+}
+
+// ErrOffsetWithoutLimit is returned for a query with an
+// offset but no limit, which sqlite and mysql reject.
+var ErrOffsetWithoutLimit = errors.New("db: query offset requires a limit")
+
+// Query builds a SELECT statement from conditions on the
+// columns of a table. It is used by the generated, typed
+// query builders, which only pass the names of the table's
+// columns; the values are always passed as parameters.
+type Query struct {
+	stmt    string
+	dialect Dialect
+	key     []string
+	order   []string
+	where   []string
+	args    []interface{}
+	sort    []string
+	limit   *int64
+	offset  *int64
+	err     error
+}
+
+// NewQuery returns a query starting with the statement,
+// SELECT ... FROM table, ordered by the default order unless
+// sorted with OrderBy. The key columns are sorted by last,
+// after those of OrderBy, so that the order is deterministic.
+func NewQuery(stmt string, d Dialect, key []string, order ...string) *Query {
+	// the generated statements are quoted with a trailing
+	// newline, which would leave a blank line before WHERE.
+	stmt = strings.TrimRight(stmt, "\n")
+	return &Query{stmt: stmt, dialect: d, key: key, order: order}
+}
+
+// Where adds the condition comparing the column with the
+// values, which must number one for the comparisons and
+// Like, two for Between, none for IsNull and IsNotNull, and
+// any for In.
+func (q *Query) Where(column string, op Op, values ...interface{}) *Query {
+	var want int
+	switch op {
+	case Eq, Ne, Lt, Le, Gt, Ge, Like:
+		want = 1
+	case Between:
+		want = 2
+	case IsNull, IsNotNull:
+		want = 0
+	case In:
+		want = len(values)
+	default:
+		q.fail(fmt.Errorf("db: invalid operator %d for %s", op, column))
+		return q
+	}
+	if len(values) != want {
+		q.fail(fmt.Errorf("db: operator %d for %s takes %d values, got %d", op, column, want, len(values)))
+		return q
+	}
+
+	column = q.dialect.Quote(column)
+	var cond string
+	switch op {
+	case Between:
+		cond = column + " BETWEEN " + q.param(values[0]) + " AND " + q.param(values[1])
+	case IsNull:
+		cond = column + " IS NULL"
+	case IsNotNull:
+		cond = column + " IS NOT NULL"
+	case In:
+		// an empty list matches no rows, as IN () is
+		// not valid SQL.
+		if len(values) == 0 {
+			cond = "1=0"
+			break
+		}
+		var buf bytes.Buffer
+		for i, value := range values {
+			if i != 0 {
+				buf.WriteString(",")
+			}
+			buf.WriteString(q.param(value))
+		}
+		cond = column + " IN (" + buf.String() + ")"
+	default:
+		cond = column + opSymbols[op] + q.param(values[0])
+	}
+	q.where = append(q.where, cond)
+	return q
+}
+
+// OrderBy sorts the results by the column, in place of
+// the default order. It may be called more than once.
+func (q *Query) OrderBy(column string, dir Direction) *Query {
+	q.sort = append(q.sort, q.dialect.Quote(column)+" "+dir.String())
+	return q
+}
+
+// Limit limits the number of results.
+func (q *Query) Limit(n int64) *Query {
+	q.limit = &n
+	return q
+}
+
+// Offset skips the first n results. It requires a limit.
+func (q *Query) Offset(n int64) *Query {
+	q.offset = &n
+	return q
+}
+
+// SQL returns the statement and its parameters, or the
+// first error in building the query.
+func (q *Query) SQL() (string, []interface{}, error) {
+	if q.err != nil {
+		return "", nil, q.err
+	}
+	if q.offset != nil && q.limit == nil {
+		return "", nil, ErrOffsetWithoutLimit
+	}
+
+	// the limit and offset are appended to a copy, so
+	// that calling SQL again does not change the query.
+	args := append([]interface{}(nil), q.args...)
+	var buf bytes.Buffer
+	buf.WriteString(q.stmt)
+	for i, cond := range q.where {
+		if i == 0 {
+			buf.WriteString("\nWHERE ")
+		} else {
+			buf.WriteString("\nAND ")
+		}
+		buf.WriteString(cond)
+	}
+
+	order := q.order
+	if len(q.sort) != 0 {
+		order = append([]string(nil), q.sort...)
+		for _, column := range q.key {
+			order = append(order, q.dialect.Quote(column))
+		}
+	}
+	for i, part := range order {
+		if i == 0 {
+			buf.WriteString("\nORDER BY ")
+		} else {
+			buf.WriteString(",")
+		}
+		buf.WriteString(part)
+	}
+
+	if q.limit != nil {
+		buf.WriteString("\nLIMIT " + q.placeholder(len(args)))
+		args = append(args, *q.limit)
+	}
+	if q.offset != nil {
+		buf.WriteString(" OFFSET " + q.placeholder(len(args)))
+		args = append(args, *q.offset)
+	}
+	return buf.String(), args, nil
+}
+
+// param adds the value to the parameters and returns its
+// placeholder.
+func (q *Query) param(value interface{}) string {
+	q.args = append(q.args, value)
+	return q.placeholder(len(q.args) - 1)
+}
+
+func (q *Query) placeholder(i int) string {
+	return q.dialect.Param(i)
+}
+
+func (q *Query) fail(err error) {
+	if q.err == nil {
+		q.err = err
+	}
+}
+
+// UpdateStmt returns the statement updating the columns of
+// the rows matching the keys. The parameters are the values
+// of the columns followed by those of the keys.
+func UpdateStmt(table string, columns, keys []string, d Dialect) string {
+	var buf bytes.Buffer
+	buf.WriteString("UPDATE " + table + " SET ")
+	for i, column := range columns {
+		if i != 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString(column + "=" + d.Param(i))
+	}
+	for i, key := range keys {
+		if i == 0 {
+			buf.WriteString("\nWHERE ")
+		} else {
+			buf.WriteString("\nAND ")
+		}
+		buf.WriteString(key + "=" + d.Param(len(columns)+i))
+	}
+	return buf.String()
+}
@@ -0,0 +1,114 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	const stmt = "SELECT f_id\nFROM issues"
+
+	var tests = []struct {
+		query *Query
+		sql   string
+		args  []interface{}
+	}{
+		{
+			NewQuery(stmt, SQLite, []string{"f_id"}, "f_created DESC", "f_id"),
+			stmt + "\nORDER BY f_created DESC,f_id",
+			nil,
+		},
+		{
+			NewQuery(stmt, SQLite, []string{"f_id"}, "f_id").
+				Where("f_state", Eq, "open").
+				Where("f_number", Gt, 10).
+				OrderBy("f_created", Desc).
+				Limit(20),
+			stmt + "\nWHERE \"f_state\"=?\nAND \"f_number\">?\nORDER BY \"f_created\" DESC,\"f_id\"\nLIMIT ?",
+			[]interface{}{"open", 10, int64(20)},
+		},
+		{
+			NewQuery(stmt, Postgres, nil).
+				Where("f_number", In, 1, 2, 3).
+				Where("f_created", Between, "a", "b").
+				Where("f_closed", IsNull).
+				Where("f_title", Like, "%bug%").
+				Limit(10).
+				Offset(5),
+			stmt + "\nWHERE \"f_number\" IN ($1,$2,$3)\nAND \"f_created\" BETWEEN $4 AND $5\nAND \"f_closed\" IS NULL\nAND \"f_title\" LIKE $6\nLIMIT $7 OFFSET $8",
+			[]interface{}{1, 2, 3, "a", "b", "%bug%", int64(10), int64(5)},
+		},
+		{
+			NewQuery(stmt, MySQL, []string{"f_user_id", "f_group_id"}).
+				OrderBy("f_role", Asc),
+			stmt + "\nORDER BY `f_role` ASC,`f_user_id`,`f_group_id`",
+			nil,
+		},
+		{
+			NewQuery(stmt, SQLite, nil).Where("f_number", In),
+			stmt + "\nWHERE 1=0",
+			nil,
+		},
+	}
+
+	for _, test := range tests {
+		sql, args, err := test.query.SQL()
+		if err != nil {
+			t.Errorf("Wanted query %q, got error %s", test.sql, err)
+			continue
+		}
+		if sql != test.sql {
+			t.Errorf("Wanted query %q, got %q", test.sql, sql)
+		}
+		if !reflect.DeepEqual(args, test.args) {
+			t.Errorf("Wanted args %v, got %v", test.args, args)
+		}
+	}
+}
+
+func TestQueryArgs(t *testing.T) {
+	// the parameters of the conditions have room for
+	// more, which the limit must not be appended into.
+	q := NewQuery("SELECT f_id\nFROM issues", SQLite, nil).Where("f_id", In, 1, 2, 3)
+	q.Limit(10)
+	_, args, err := q.SQL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.Where("f_state", Eq, "open")
+	if want := []interface{}{1, 2, 3, int64(10)}; !reflect.DeepEqual(args, want) {
+		t.Errorf("Wanted args %v left as they were, got %v", want, args)
+	}
+}
+
+func TestQueryError(t *testing.T) {
+	var tests = []*Query{
+		NewQuery("", SQLite, nil).Where("f_id", Eq),
+		NewQuery("", SQLite, nil).Where("f_id", Between, 1),
+		NewQuery("", SQLite, nil).Where("f_id", IsNull, 1),
+		NewQuery("", SQLite, nil).Where("f_id", Op(-1), 1),
+		NewQuery("", SQLite, nil).Offset(10),
+	}
+
+	for _, query := range tests {
+		if _, _, err := query.SQL(); err == nil {
+			t.Errorf("Wanted error for invalid query")
+		}
+	}
+}
+
+func TestUpdateStmt(t *testing.T) {
+	var tests = []struct {
+		got  string
+		want string
+	}{
+		{UpdateStmt("issues", []string{"f_title"}, []string{"f_id"}, SQLite), "UPDATE issues SET f_title=?\nWHERE f_id=?"},
+		{UpdateStmt("members", []string{"f_role", "f_since"}, []string{"f_user_id", "f_group_id"}, Postgres), "UPDATE members SET f_role=$1,f_since=$2\nWHERE f_user_id=$3\nAND f_group_id=$4"},
+	}
+
+	for _, test := range tests {
+		if test.got != test.want {
+			t.Errorf("Wanted update %q, got %q", test.want, test.got)
+		}
+	}
+}
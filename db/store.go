@@ -0,0 +1,175 @@
+package db
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Now returns the current time. It is used by the generated
+// functions to set the automatic timestamps, and by the
+// in-memory stores to mark rows as deleted, and may be replaced
+// in tests with a fixed clock.
+var Now = time.Now
+
+// Equal reports whether two field values are equal, as
+// used by the generated in-memory stores. Times are compared
+// with time.Time.Equal, other values with reflect.DeepEqual.
+func Equal(a, b interface{}) bool {
+	if t, ok := a.(time.Time); ok {
+		u, ok := b.(time.Time)
+		return ok && t.Equal(u)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// Less reports whether the key values a sort before the
+// key values b, comparing them in order, as the database
+// compares row values. Keys are never NULL.
+func Less(a, b []interface{}) bool {
+	for i := range a {
+		if c := compare(a[i], b[i], true); c != 0 {
+			return c < 0
+		}
+	}
+	return false
+}
+
+// SortByKey sorts the slice by the key values of each of
+// its elements, as used by the generated in-memory stores.
+func SortByKey(slice interface{}, key func(i int) []interface{}) {
+	sort.SliceStable(slice, func(i, j int) bool {
+		return Less(key(i), key(j))
+	})
+}
+
+// SortByColumn stably sorts the slice by the value of a
+// column of each of its elements, in descending order when
+// desc is true, as used by the generated in-memory stores.
+// Nil pointers are NULL, sorted as by the dialect.
+func SortByColumn(slice interface{}, value func(i int) interface{}, desc bool, d Dialect) {
+	SortByColumns(slice, func(i int) []interface{} {
+		return []interface{}{value(i)}
+	}, []bool{desc}, d)
+}
+
+// SortByColumns stably sorts the slice by the values of
+// columns of each of its elements, in turn, each in
+// descending order when its desc is true, as by an ORDER BY
+// clause of the columns.
+func SortByColumns(slice interface{}, values func(i int) []interface{}, desc []bool, d Dialect) {
+	sort.SliceStable(slice, func(i, j int) bool {
+		a, b := values(i), values(j)
+		for k := range a {
+			c := compare(a[k], b[k], d.NullsFirst())
+			if c == 0 {
+				continue
+			}
+			if desc[k] {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+}
+
+// Clone replaces the pointer, slice or map ptr points to
+// with a copy, as used by the generated in-memory stores so
+// that the rows they return share no memory with the stored
+// rows. The elements of a slice or map are copied as-is.
+func Clone(ptr interface{}) {
+	v := reflect.ValueOf(ptr).Elem()
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			c := reflect.New(v.Type().Elem())
+			c.Elem().Set(v.Elem())
+			v.Set(c)
+		}
+	case reflect.Slice:
+		if !v.IsNil() {
+			c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+			reflect.Copy(c, v)
+			v.Set(c)
+		}
+	case reflect.Map:
+		if !v.IsNil() {
+			c := reflect.MakeMapWithSize(v.Type(), v.Len())
+			for iter := v.MapRange(); iter.Next(); {
+				c.SetMapIndex(iter.Key(), iter.Value())
+			}
+			v.Set(c)
+		}
+	}
+}
+
+// compare compares two column values, dereferencing
+// pointers. A nil pointer, or nil, is NULL, which sorts
+// before any other value when nullsFirst is true and after
+// it otherwise.
+func compare(a, b interface{}, nullsFirst bool) int {
+	a, b = deref(a), deref(b)
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return compareOrdered(nullsFirst, !nullsFirst)
+	case b == nil:
+		return compareOrdered(!nullsFirst, nullsFirst)
+	}
+
+	if t, ok := a.(time.Time); ok {
+		u, _ := b.(time.Time)
+		switch {
+		case t.Before(u):
+			return -1
+		case t.After(u):
+			return 1
+		}
+		return 0
+	}
+
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.Kind() != vb.Kind() {
+		return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+	}
+	switch va.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return compareOrdered(va.Int() < vb.Int(), va.Int() > vb.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return compareOrdered(va.Uint() < vb.Uint(), va.Uint() > vb.Uint())
+	case reflect.Float32, reflect.Float64:
+		return compareOrdered(va.Float() < vb.Float(), va.Float() > vb.Float())
+	case reflect.String:
+		return strings.Compare(va.String(), vb.String())
+	case reflect.Bool:
+		return compareOrdered(!va.Bool() && vb.Bool(), va.Bool() && !vb.Bool())
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// deref returns the value the pointer points to, or nil
+// for a nil pointer. Other values are returned as they are.
+func deref(v interface{}) interface{} {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr {
+		return v
+	}
+	if rv.IsNil() {
+		return nil
+	}
+	return rv.Elem().Interface()
+}
+
+func compareOrdered(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
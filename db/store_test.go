@@ -0,0 +1,106 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+var lessTests = []struct {
+	a, b []interface{}
+	want bool
+}{
+	{[]interface{}{int64(1)}, []interface{}{int64(2)}, true},
+	{[]interface{}{int64(2)}, []interface{}{int64(2)}, false},
+	{[]interface{}{int64(1), "b"}, []interface{}{int64(1), "a"}, false},
+	{[]interface{}{int64(1), "a"}, []interface{}{int64(1), "b"}, true},
+	{[]interface{}{time.Unix(1, 0)}, []interface{}{time.Unix(2, 0)}, true},
+}
+
+func TestLess(t *testing.T) {
+	for _, test := range lessTests {
+		if got := Less(test.a, test.b); got != test.want {
+			t.Errorf("Wanted %v < %v to be %v", test.a, test.b, test.want)
+		}
+	}
+}
+
+func TestSortByColumns(t *testing.T) {
+	type row struct {
+		state string
+		id    int64
+	}
+	rows := []row{{"open", 1}, {"closed", 2}, {"open", 3}, {"closed", 4}}
+
+	// ORDER BY f_state, f_id DESC
+	SortByColumns(rows, func(i int) []interface{} {
+		return []interface{}{rows[i].state, rows[i].id}
+	}, []bool{false, true}, SQLite)
+
+	want := []row{{"closed", 4}, {"closed", 2}, {"open", 3}, {"open", 1}}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("Wanted rows sorted %v, got %v", want, rows)
+	}
+}
+
+func TestClone(t *testing.T) {
+	n := 1
+	v := struct {
+		ptr   *int
+		bytes []byte
+		tags  map[string]string
+		none  []string
+	}{&n, []byte("a"), map[string]string{"a": "b"}, nil}
+	c := v
+	Clone(&c.ptr)
+	Clone(&c.bytes)
+	Clone(&c.tags)
+	Clone(&c.none)
+
+	*c.ptr = 2
+	c.bytes[0] = 'b'
+	c.tags["a"] = "c"
+	if *v.ptr != 1 || string(v.bytes) != "a" || v.tags["a"] != "b" {
+		t.Errorf("Wanted the copies not to share memory, got %v", v)
+	}
+	if c.none != nil {
+		t.Errorf("Wanted a nil slice left nil, got %v", c.none)
+	}
+}
+
+func TestSortByColumnNulls(t *testing.T) {
+	one, two := 1, 2
+	var tests = []struct {
+		dialect Dialect
+		desc    bool
+		want    []*int
+	}{
+		{SQLite, false, []*int{nil, nil, &one, &two}},
+		{SQLite, true, []*int{&two, &one, nil, nil}},
+		{MySQL, false, []*int{nil, nil, &one, &two}},
+		{Postgres, false, []*int{&one, &two, nil, nil}},
+		{Postgres, true, []*int{nil, nil, &two, &one}},
+	}
+
+	for _, test := range tests {
+		// distinct pointers to equal values, which must
+		// not be sorted by their address.
+		a, b := 2, 1
+		values := []*int{&a, nil, &b, nil}
+		SortByColumn(values, func(i int) interface{} {
+			return values[i]
+		}, test.desc, test.dialect)
+
+		if !reflect.DeepEqual(values, test.want) {
+			t.Errorf("Wanted %T sorted with desc %v as %v, got %v", test.dialect, test.desc, ints(test.want), ints(values))
+		}
+	}
+}
+
+func ints(vv []*int) []interface{} {
+	var values []interface{}
+	for _, v := range vv {
+		values = append(values, deref(v))
+	}
+	return values
+}
@@ -0,0 +1,150 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// TxOptions holds the options of a transaction started
+// by WithTx.
+type TxOptions struct {
+	// Isolation and ReadOnly are passed on to BeginTx.
+	Isolation sql.IsolationLevel
+	ReadOnly  bool
+
+	// Retries is the number of times the transaction is
+	// run again after failing with a serialization failure
+	// or deadlock, see Retryable.
+	Retries int
+}
+
+// Tx is the transaction handed to the function run by
+// WithTx. It satisfies both SimpleDB and ContextDB, so the
+// generated functions may be called with it unchanged, and
+// it may be passed to Savepoint to nest a transaction.
+type Tx struct {
+	*sql.Tx
+
+	// depth is the number of enclosing savepoints,
+	// used to give each savepoint a unique name.
+	depth int
+}
+
+// WithTx runs fn in a transaction, committing it if fn
+// returns nil and rolling it back if fn returns an error or
+// panics. A panic is re-raised once the transaction has been
+// rolled back. The opts may be nil.
+func WithTx(ctx context.Context, db *sql.DB, opts *TxOptions, fn func(SimpleDB) error) error {
+	if opts == nil {
+		opts = new(TxOptions)
+	}
+	txOpts := &sql.TxOptions{Isolation: opts.Isolation, ReadOnly: opts.ReadOnly}
+
+	for i := 0; ; i++ {
+		err := runTx(ctx, db, txOpts, fn)
+		if err == nil || i >= opts.Retries || !Retryable(err) {
+			return err
+		}
+	}
+}
+
+func runTx(ctx context.Context, db *sql.DB, opts *sql.TxOptions, fn func(SimpleDB) error) (err error) {
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err = fn(&Tx{Tx: tx}); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Savepoint runs fn nested in the transaction db, inside a
+// SAVEPOINT that is released if fn returns nil and rolled
+// back to if fn returns an error or panics, leaving the
+// enclosing transaction usable. If db is a *sql.DB, rather
+// than a *Tx from WithTx, a new transaction is started.
+func Savepoint(ctx context.Context, db SimpleDB, fn func(SimpleDB) error) (err error) {
+	var tx *Tx
+	switch v := db.(type) {
+	case *Tx:
+		tx = v
+	case *sql.DB:
+		return WithTx(ctx, v, nil, fn)
+	default:
+		return fmt.Errorf("db: cannot nest a transaction in %T", db)
+	}
+
+	name := fmt.Sprintf("sp_%d", tx.depth+1)
+	if _, err = tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+			panic(p)
+		}
+	}()
+
+	if err = fn(&Tx{Tx: tx.Tx, depth: tx.depth + 1}); err != nil {
+		tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	return err
+}
+
+// Atomic runs fn on db, in a transaction started by WithTx
+// if db is a *sql.DB, so that the statements of fn are
+// applied together or not at all. Any other db, such as a
+// *Tx, is passed to fn as is, leaving it to the caller to
+// roll back on error.
+func Atomic(db SimpleDB, fn func(SimpleDB) error) error {
+	if v, ok := db.(*sql.DB); ok {
+		return WithTx(context.Background(), v, nil, fn)
+	}
+	return fn(db)
+}
+
+// AtomicContext is Atomic for a ContextDB, passing ctx on
+// to the transaction.
+func AtomicContext(ctx context.Context, db ContextDB, fn func(ContextDB) error) error {
+	if v, ok := db.(*sql.DB); ok {
+		return WithTx(ctx, v, nil, func(tx SimpleDB) error {
+			return fn(tx.(*Tx))
+		})
+	}
+	return fn(db)
+}
+
+// Retryable returns true if the error is a serialization
+// failure or deadlock, after which the transaction may
+// succeed if run again. Errors are matched by SQLSTATE when
+// the driver reports it, as the postgres drivers do, and
+// otherwise by the mysql and sqlite error messages.
+func Retryable(err error) bool {
+	var state interface{ SQLState() string }
+	if errors.As(err, &state) {
+		switch state.SQLState() {
+		case "40001", "40P01":
+			return true
+		}
+		return false
+	}
+
+	msg := err.Error()
+	return strings.Contains(msg, "Deadlock found") ||
+		strings.Contains(msg, "try restarting transaction") ||
+		strings.Contains(msg, "database is locked")
+}
@@ -0,0 +1,194 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// logDriver is a database/sql driver that records the
+// statements and transaction calls made through it.
+type logDriver struct {
+	log []string
+}
+
+func (d *logDriver) Open(name string) (driver.Conn, error) { return &logConn{d}, nil }
+
+type logConn struct{ d *logDriver }
+
+func (c *logConn) Prepare(query string) (driver.Stmt, error) { return &logStmt{c.d, query}, nil }
+func (c *logConn) Close() error                              { return nil }
+func (c *logConn) Begin() (driver.Tx, error) {
+	c.d.log = append(c.d.log, "BEGIN")
+	return c, nil
+}
+func (c *logConn) Commit() error {
+	c.d.log = append(c.d.log, "COMMIT")
+	return nil
+}
+func (c *logConn) Rollback() error {
+	c.d.log = append(c.d.log, "ROLLBACK")
+	return nil
+}
+
+type logStmt struct {
+	d     *logDriver
+	query string
+}
+
+func (s *logStmt) Close() error  { return nil }
+func (s *logStmt) NumInput() int { return -1 }
+func (s *logStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.log = append(s.d.log, s.query)
+	return driver.RowsAffected(0), nil
+}
+func (s *logStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+var drivers int
+
+func openLog(t *testing.T) (*sql.DB, *logDriver) {
+	d := new(logDriver)
+	drivers++
+	name := fmt.Sprintf("log%d", drivers)
+	sql.Register(name, d)
+	db, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, d
+}
+
+type stateError string
+
+func (e stateError) Error() string    { return "state " + string(e) }
+func (e stateError) SQLState() string { return string(e) }
+
+func TestWithTx(t *testing.T) {
+	ctx := context.Background()
+	errFail := errors.New("fail")
+
+	var tests = []struct {
+		opts *TxOptions
+		fn   func(calls int) func(SimpleDB) error
+		err  error
+		log  []string
+	}{
+		// commit
+		{nil, func(int) func(SimpleDB) error {
+			return func(db SimpleDB) error {
+				_, err := db.Exec("INSERT")
+				return err
+			}
+		}, nil, []string{"BEGIN", "INSERT", "COMMIT"}},
+
+		// rollback on error
+		{nil, func(int) func(SimpleDB) error {
+			return func(db SimpleDB) error { return errFail }
+		}, errFail, []string{"BEGIN", "ROLLBACK"}},
+
+		// retry on serialization failure
+		{&TxOptions{Retries: 2}, func(calls int) func(SimpleDB) error {
+			return func(db SimpleDB) error {
+				if calls == 0 {
+					return stateError("40001")
+				}
+				return nil
+			}
+		}, nil, []string{"BEGIN", "ROLLBACK", "BEGIN", "COMMIT"}},
+
+		// no retry on other errors
+		{&TxOptions{Retries: 2}, func(int) func(SimpleDB) error {
+			return func(db SimpleDB) error { return stateError("23505") }
+		}, stateError("23505"), []string{"BEGIN", "ROLLBACK"}},
+
+		// savepoints
+		{nil, func(int) func(SimpleDB) error {
+			return func(db SimpleDB) error {
+				Savepoint(ctx, db, func(db SimpleDB) error {
+					return Savepoint(ctx, db, func(db SimpleDB) error { return nil })
+				})
+				return Savepoint(ctx, db, func(db SimpleDB) error { return errFail })
+			}
+		}, errFail, []string{
+			"BEGIN",
+			"SAVEPOINT sp_1", "SAVEPOINT sp_2", "RELEASE SAVEPOINT sp_2", "RELEASE SAVEPOINT sp_1",
+			"SAVEPOINT sp_1", "ROLLBACK TO SAVEPOINT sp_1",
+			"ROLLBACK",
+		}},
+	}
+
+	for _, test := range tests {
+		db, d := openLog(t)
+		var calls int
+		err := WithTx(ctx, db, test.opts, func(tx SimpleDB) error {
+			defer func() { calls++ }()
+			return test.fn(calls)(tx)
+		})
+		if err != test.err {
+			t.Errorf("Wanted error %v, got %v", test.err, err)
+		}
+		if !reflect.DeepEqual(d.log, test.log) {
+			t.Errorf("Wanted calls %q, got %q", test.log, d.log)
+		}
+	}
+}
+
+func TestWithTxPanic(t *testing.T) {
+	db, d := openLog(t)
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Wanted panic to be re-raised")
+		}
+		want := []string{"BEGIN", "ROLLBACK"}
+		if !reflect.DeepEqual(d.log, want) {
+			t.Errorf("Wanted calls %q, got %q", want, d.log)
+		}
+	}()
+	WithTx(context.Background(), db, nil, func(SimpleDB) error {
+		panic("boom")
+	})
+}
+
+func TestAtomic(t *testing.T) {
+	ctx := context.Background()
+	insert := func(db SimpleDB) error {
+		_, err := db.Exec("INSERT")
+		return err
+	}
+	insertContext := func(db ContextDB) error {
+		_, err := db.ExecContext(ctx, "INSERT")
+		return err
+	}
+
+	// a *sql.DB is given a transaction
+	db, d := openLog(t)
+	if err := Atomic(db, insert); err != nil {
+		t.Fatal(err)
+	}
+	if err := AtomicContext(ctx, db, insertContext); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"BEGIN", "INSERT", "COMMIT", "BEGIN", "INSERT", "COMMIT"}
+	if !reflect.DeepEqual(d.log, want) {
+		t.Errorf("Wanted calls %q, got %q", want, d.log)
+	}
+
+	// a transaction is used as is
+	db, d = openLog(t)
+	WithTx(ctx, db, nil, func(tx SimpleDB) error {
+		if err := Atomic(tx, insert); err != nil {
+			return err
+		}
+		return AtomicContext(ctx, tx.(*Tx), insertContext)
+	})
+	want = []string{"BEGIN", "INSERT", "INSERT", "COMMIT"}
+	if !reflect.DeepEqual(d.log, want) {
+		t.Errorf("Wanted calls %q, got %q", want, d.log)
+	}
+}
@@ -0,0 +1,30 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// ErrStaleObject is returned by the update of a row with a
+// version column when the row no longer has the version that
+// was read, because it was updated or deleted since.
+type ErrStaleObject struct {
+	Table string
+}
+
+func (e *ErrStaleObject) Error() string {
+	return fmt.Sprintf("db: stale %s row", e.Table)
+}
+
+// CheckVersion returns an ErrStaleObject for the table if
+// the versioned update with the result changed no rows.
+func CheckVersion(res sql.Result, table string) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return &ErrStaleObject{Table: table}
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package db
+
+import (
+	"database/sql/driver"
+	"testing"
+)
+
+func TestCheckVersion(t *testing.T) {
+	if err := CheckVersion(driver.RowsAffected(1), "docs"); err != nil {
+		t.Errorf("Wanted no error for an updated row, got %s", err)
+	}
+	err := CheckVersion(driver.RowsAffected(0), "docs")
+	if stale, ok := err.(*ErrStaleObject); !ok || stale.Table != "docs" {
+		t.Errorf("Wanted stale docs row, got %v", err)
+	}
+}
@@ -14,18 +14,25 @@ import (
 )
 
 var (
-	input      = flag.String("file", "", "input file name; required")
+	input      = flag.String("file", "", "input file name; required unless -path")
+	inputPath  = flag.String("path", "", "input package path or directory; used in place of -file")
 	output     = flag.String("o", "", "output file name; required")
 	outputSql  = flag.String("osf", "", "output sql file path;")
 	pkgName    = flag.String("pkg", "main", "output package name; required")
 	srcPkgName = flag.String("srcPkg", "main", "input package name; required")
-	typeName   = flag.String("type", "", "type to generate; required")
+	typeName   = flag.String("type", "", "comma-separated list of types to generate; required unless -all")
+	allTypes   = flag.Bool("all", false, "generate every struct type with sql tags")
 	database   = flag.String("db", "sqlite", "sql dialect; required")
 	genSchema  = flag.Bool("schema", true, "generate sql schema and queries")
 	genFuncs   = flag.Bool("funcs", true, "generate sql helper functions")
 	extraFuncs = flag.Bool("extras", true, "generate extra sql helper functions")
 	needImport = flag.Bool( "needImport", true, "need to generate import statement")
 	view       = flag.Bool("view", false, "is view, not table")
+	returning  = flag.Bool("returning", false, "read generated keys with INSERT ... RETURNING; for sqlite 3.35+ and mariadb, always on for postgres")
+	api        = flag.String("api", apiLegacy, "generated function api; legacy, context or both")
+	genStore   = flag.Bool("store", false, "generate a store interface, sql implementation and in-memory fake per table")
+	updateKeys = flag.Bool("updateKeys", true, "include the primary key and auto columns in the SET list of generated updates")
+	valuers    = flag.String("valuers", "", "comma-separated list of type=sqltype mappings for sql.Scanner types")
 )
 
 func init() {
@@ -37,7 +44,7 @@ func main() {
 
 	// parses the syntax tree into something a bit
 	// easier to work with.
-	tree, err := parse.Parse(*input, *typeName)
+	trees, err := parseTrees()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "%v\n", err)
 		os.Exit(1)
@@ -45,99 +52,252 @@ func main() {
 
 	// if the code is generated in a different folder
 	// that the struct we need to import the struct
-	if tree.Pkg != *pkgName && *pkgName != "main" {
+	if trees[0].Pkg != *pkgName && *pkgName != "main" {
 		// TODO
 	}
 
-	// load the Tree into a schema Object
-	table := schema.Load(tree)
-	dialect := schema.New(schema.Dialects[*database])
-	strs:=strings.Split(*srcPkgName, "/")
-	srcPkgNameInShort:=strs[len(strs)-1]
-
+	switch *api {
+	case apiLegacy, apiContext, apiBoth:
+	default:
+		fmt.Fprintf(os.Stderr, "invalid api %q; legacy, context or both\n", *api)
+		os.Exit(1)
+	}
 
-	var buf bytes.Buffer
+	if err := loadValuers(*valuers); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
 
-	if *needImport{
-		writePackage(&buf, *pkgName)
-		writeImports(&buf, tree, "database/sql", "github.com/linchunquan/sqlgen/db", *srcPkgName)
+	pretty, err := generate(trees)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return
 	}
 
-	// write the sql functions
-	if *genSchema {
-		writeSchema(&buf, dialect, table, *outputSql, *view)
+	// create output source for file. defaults to
+	// stdout but may be file.
+	var out io.WriteCloser = os.Stdout
+	if *output != "" {
+		out, err = os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return
+		}
+		defer out.Close()
 	}
 
-	log.Printf("Finish write the sql functions for table %s\n", table.Name)
+	io.Copy(out, pretty)
+}
 
-	if *genFuncs {
+// generate writes the package header, the imports and the
+// code of each parsed type, as set by the flags, and formats
+// it with gofmt.
+func generate(trees []*parse.Node) (io.Reader, error) {
+	dialect := schema.New(schema.Dialects[*database])
+	if *returning {
+		schema.EnableReturning(dialect)
+	}
+	strs:=strings.Split(*srcPkgName, "/")
+	srcPkgNameInShort:=strs[len(strs)-1]
 
-		writeRowFunc(srcPkgNameInShort, &buf, tree)
-		log.Printf("Finish writeRowFunc for table %s\n", table.Name)
-		writeRowsFunc(srcPkgNameInShort, &buf, tree)
-		log.Printf("Finish writeRowsFunc for table %s\n", table.Name)
-		writeSliceFunc(srcPkgNameInShort, &buf, tree)
-		log.Printf("Finish writeSliceFunc for table %s\n", table.Name)
 
+	var buf bytes.Buffer
+
+	// the package header and a single import block
+	// are shared by every table in the output file.
+	switch {
+	case *needImport && *genFuncs:
+		pkgs := []string{"database/sql", "github.com/linchunquan/sqlgen/db", *srcPkgName}
 		if *extraFuncs {
-			writeGenericSelectRow(srcPkgNameInShort, &buf, tree)
-			log.Printf("Finish writeGenericSelectRow for table %s\n", table.Name)
-			writeGenericSelectRows(srcPkgNameInShort, &buf, tree)
-			log.Printf("Finish writeGenericSelectRows for table %s\n", table.Name)
-			//writeGenericInsertFunc(srcPkgNameInShort, &buf, tree)
-			//writeGenericUpdateFunc(srcPkgNameInShort, &buf, tree)
-			if !*view {
-				writeInsertFunc(srcPkgNameInShort, &buf, tree, table)
-				log.Printf("Finish writeInsertFunc for table %s\n", table.Name)
-				writeDeleteFunc(srcPkgNameInShort, &buf, tree, table)
-				log.Printf("Finish writeDeleteFunc for table %s\n", table.Name)
-				writeUpdateFunc(srcPkgNameInShort, &buf, tree, table)
-				log.Printf("Finish writeUpdateFunc for table %s\n", table.Name)
-			}
-			writeGetByFunc(srcPkgNameInShort, &buf, tree, table)
-			log.Printf("Finish writeGetByFunc for table %s\n", table.Name)
-			writeFindAllFunc(srcPkgNameInShort, &buf, tree, table)
-			log.Printf("Finish writeFindAllFunc for table %s\n", table.Name)
-			writeFindAllInRangeFunc(srcPkgNameInShort, &buf, tree, table)
-			log.Printf("Finish writeFindAllInRangeFunc for table %s\n", table.Name)
-			writeFindByIndexFunc(srcPkgNameInShort, &buf, tree, table)
-			log.Printf("Finish writeFindByIndexFunc for table %s\n", table.Name)
-			writeFindByForeignKeyFunc(srcPkgNameInShort, &buf, tree, table)
-			log.Printf("Finish writeFindByForeignKeyFunc for table %s\n", table.Name)
-			writeCountAllFunc(&buf,tree,table)
-			log.Printf("Finish writeCountAllFunc for table %s\n", table.Name)
-			writeCountByIndexFunc(&buf,tree,table)
-			log.Printf("Finish writeCountByIndexFunc for table %s\n", table.Name)
+			pkgs = append(pkgs, "fmt")
+		}
+		if *extraFuncs && *api != apiLegacy {
+			pkgs = append(pkgs, "context")
 		}
-	} else {
 		writePackage(&buf, *pkgName)
-		log.Printf("Finish writePackage for table %s\n", table.Name)
+		writeImports(&buf, trees, pkgs...)
+	case !*genFuncs:
+		writePackage(&buf, *pkgName)
 	}
 
-	log.Printf("Generate content for table %s\n", table.Name)
+	for _, tree := range trees {
+		writeTable(&buf, dialect, tree, srcPkgNameInShort)
+	}
+
+	log.Printf("Generate content for types %s\n", joinTypes(trees))
 	log.Println("==================================================================")
 	log.Printf("%s\n", buf.Bytes())
 	log.Println("==================================================================")
 
 	// formats the generated file using gofmt
 	pretty, err := format(&buf)
-	log.Printf("Finish format for table %s\n, err:%v\n", table.Name, err)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
+	log.Printf("Finish format for types %s\n, err:%v\n", joinTypes(trees), err)
+	return pretty, err
+}
+
+// parseTrees parses every type requested on the command
+// line, either by the comma-separated -type list or by
+// the -all flag.
+func parseTrees() ([]*parse.Node, error) {
+	var src = *input
+	if *inputPath != "" {
+		src = *inputPath
+	}
+
+	if *allTypes {
+		return parse.ParseAll(src)
+	}
+
+	var names []string
+	for _, name := range strings.Split(*typeName, ",") {
+		name = strings.TrimSpace(name)
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, parse.ErrTypeNotFound
+	}
+	return parse.ParseTypes(src, names...)
+}
+
+// loadValuers adds the type=sqltype pairs to the column
+// types used for sql.Scanner and driver.Valuer fields,
+// for example "uuid.UUID=varchar,Money=long".
+func loadValuers(list string) error {
+	for _, pair := range strings.Split(list, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid valuer mapping %q", pair)
+		}
+		t, ok := schema.SqlType(strings.TrimSpace(parts[1]))
+		if !ok {
+			return fmt.Errorf("unknown sql type %q for %s", parts[1], parts[0])
+		}
+		schema.ValuerTypes[strings.TrimSpace(parts[0])] = t
+	}
+	return nil
+}
+
+// writeTable writes the sql statements and helper
+// functions for a single parsed type to w.
+func writeTable(w io.Writer, dialect schema.Dialect, tree *parse.Node, srcPkgNameInShort string) {
+
+	// load the Tree into a schema Object
+	table := schema.Load(tree)
+
+	// write the sql functions
+	if *genSchema {
+		writeSchema(w, dialect, table, *outputSql, *view)
+	}
+
+	log.Printf("Finish write the sql functions for table %s\n", table.Name)
+
+	if !*genFuncs {
 		return
 	}
 
-	// create output source for file. defaults to
-	// stdout but may be file.
-	var out io.WriteCloser = os.Stdout
-	if *output != "" {
-		out, err = os.Create(*output)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "%v\n", err)
-			return
+	writeRowFunc(srcPkgNameInShort, w, tree)
+	log.Printf("Finish writeRowFunc for table %s\n", table.Name)
+	writeRowsFunc(srcPkgNameInShort, w, tree)
+	log.Printf("Finish writeRowsFunc for table %s\n", table.Name)
+	writeSliceFunc(srcPkgNameInShort, w, tree)
+	log.Printf("Finish writeSliceFunc for table %s\n", table.Name)
+
+	if !*extraFuncs {
+		return
+	}
+
+	// the batch insert statement, the timestamps, the error
+	// wrapping, the page cursors, the sort columns and the query
+	// builder are shared by the legacy and context apis.
+	if !*view {
+		writeInsertBatchQuery(w, tree, table, dialect)
+		writeUpdateFieldsQuery(srcPkgNameInShort, w, tree, table, dialect)
+		writeCheckVersion(w, tree, table)
+		writeStampTime(srcPkgNameInShort, w, tree, table)
+	}
+	writeWrapError(w, tree, table, dialect)
+	writeCursorFuncs(srcPkgNameInShort, w, tree, table)
+	writeColumns(w, tree, table)
+	writeQuery(w, tree, table, dialect)
+
+	// the extra functions are written once for each
+	// requested api, from the same templates given the
+	// form of the api.
+	if *api != apiContext {
+		writeExtraFuncs(newAPIWriter(w, legacyForm), dialect, tree, table, srcPkgNameInShort)
+	}
+	if *api != apiLegacy {
+		writeExtraFuncs(newAPIWriter(w, contextForm), dialect, tree, table, srcPkgNameInShort)
+	}
+
+	if *genStore {
+		form := legacyForm
+		if *api != apiLegacy {
+			form = contextForm
 		}
-		defer out.Close()
+		writeStore(srcPkgNameInShort, w, tree, table, dialect, form)
 	}
+}
 
-	io.Copy(out, pretty)
+// writeExtraFuncs writes the generic select functions and
+// the insert, update, delete, get, find and count helper
+// functions for a single table to w.
+func writeExtraFuncs(w io.Writer, dialect schema.Dialect, tree *parse.Node, table *schema.Table, srcPkgNameInShort string) {
+	writeGenericSelectRow(srcPkgNameInShort, w, tree)
+	log.Printf("Finish writeGenericSelectRow for table %s\n", table.Name)
+	writeGenericSelectRows(srcPkgNameInShort, w, tree)
+	log.Printf("Finish writeGenericSelectRows for table %s\n", table.Name)
+	//writeGenericInsertFunc(srcPkgNameInShort, w, tree)
+	//writeGenericUpdateFunc(srcPkgNameInShort, w, tree)
+	if !*view {
+		writeInsertFunc(srcPkgNameInShort, w, tree, table, dialect)
+		log.Printf("Finish writeInsertFunc for table %s\n", table.Name)
+		writeInsertBatchFunc(srcPkgNameInShort, w, tree, table, dialect)
+		log.Printf("Finish writeInsertBatchFunc for table %s\n", table.Name)
+		writeDeleteFunc(srcPkgNameInShort, w, tree, table)
+		log.Printf("Finish writeDeleteFunc for table %s\n", table.Name)
+		writeUpdateFunc(srcPkgNameInShort, w, tree, table)
+		log.Printf("Finish writeUpdateFunc for table %s\n", table.Name)
+		writeUpdateFieldsFunc(srcPkgNameInShort, w, tree, table)
+		log.Printf("Finish writeUpdateFieldsFunc for table %s\n", table.Name)
+		writeUpsertFunc(srcPkgNameInShort, w, tree, table, dialect)
+		log.Printf("Finish writeUpsertFunc for table %s\n", table.Name)
+	}
+	writeGetByFunc(srcPkgNameInShort, w, tree, table)
+	log.Printf("Finish writeGetByFunc for table %s\n", table.Name)
+	writeFindAllFunc(srcPkgNameInShort, w, tree, table)
+	log.Printf("Finish writeFindAllFunc for table %s\n", table.Name)
+	writeFindAllInRangeFunc(srcPkgNameInShort, w, tree, table)
+	log.Printf("Finish writeFindAllInRangeFunc for table %s\n", table.Name)
+	writeFindAfterFunc(srcPkgNameInShort, w, tree, table)
+	log.Printf("Finish writeFindAfterFunc for table %s\n", table.Name)
+	writeFindSortedFunc(srcPkgNameInShort, w, tree, table)
+	log.Printf("Finish writeFindSortedFunc for table %s\n", table.Name)
+	writeQueryAllFunc(srcPkgNameInShort, w, tree)
+	log.Printf("Finish writeQueryAllFunc for table %s\n", table.Name)
+	writeFindByIndexFunc(srcPkgNameInShort, w, tree, table)
+	log.Printf("Finish writeFindByIndexFunc for table %s\n", table.Name)
+	writeFindByForeignKeyFunc(srcPkgNameInShort, w, tree, table)
+	log.Printf("Finish writeFindByForeignKeyFunc for table %s\n", table.Name)
+	writeCountAllFunc(w,tree,table)
+	log.Printf("Finish writeCountAllFunc for table %s\n", table.Name)
+	writeCountByIndexFunc(w,tree,table)
+	log.Printf("Finish writeCountByIndexFunc for table %s\n", table.Name)
+	writeWithDeletedFuncs(srcPkgNameInShort, w, tree, table)
+	log.Printf("Finish writeWithDeletedFuncs for table %s\n", table.Name)
+}
+
+// joinTypes is a helper function that joins the type
+// names of the parsed trees for logging.
+func joinTypes(trees []*parse.Node) string {
+	var names []string
+	for _, tree := range trees {
+		names = append(names, tree.Type)
+	}
+	return strings.Join(names, ",")
 }
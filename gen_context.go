@@ -0,0 +1,62 @@
+package main
+
+import (
+	"io"
+	"strings"
+)
+
+// List of generated function apis
+const (
+	apiLegacy  = "legacy"
+	apiContext = "context"
+	apiBoth    = "both"
+)
+
+// apiForm is the form of the functions generated for an
+// api, substituted for the {{.X}} markers of the function
+// templates once they are formatted.
+type apiForm struct {
+	Ctx      string // suffix of the function names
+	Context  string // suffix of the database methods
+	CtxParam string // parameter preceding db
+	CtxArg   string // argument preceding db
+	DB       string // type of db
+}
+
+// The forms of the legacy api and of the context-aware api,
+// which takes a context.Context and a db.ContextDB and passes
+// the context on to every query.
+var (
+	legacyForm  = apiForm{DB: "db.SimpleDB"}
+	contextForm = apiForm{
+		Ctx:      "Ctx",
+		Context:  "Context",
+		CtxParam: "ctx context.Context, ",
+		CtxArg:   "ctx, ",
+		DB:       "db.ContextDB",
+	}
+)
+
+// apiWriter writes each generated function written to it
+// in the form of an api.
+type apiWriter struct {
+	w io.Writer
+	r *strings.Replacer
+}
+
+func newAPIWriter(w io.Writer, form apiForm) *apiWriter {
+	return &apiWriter{w: w, r: strings.NewReplacer(
+		"{{.Ctx}}", form.Ctx,
+		"{{.Context}}", form.Context,
+		"{{.CtxParam}}", form.CtxParam,
+		"{{.CtxArg}}", form.CtxArg,
+		"{{.DB}}", form.DB,
+	)}
+}
+
+func (a *apiWriter) Write(p []byte) (int, error) {
+	if _, err := a.r.WriteString(a.w, string(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
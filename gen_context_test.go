@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/linchunquan/sqlgen/parse"
+)
+
+func TestAPIWriter(t *testing.T) {
+	var buf bytes.Buffer
+	fmt.Fprintf(newAPIWriter(&buf, contextForm), sGetBy, "User", "ByLogin", "login string", "models.User", "\t"+getWrapCode(&parse.Node{Type: "User"}, "GetUserByLogin"), "login", "User", "selectUserByLoginStmt")
+
+	var want = `
+func GetUserByLoginCtx(ctx context.Context, db db.ContextDB, login string) (_ *models.User, err error) {
+	defer userWrapError(&err, "GetUserByLoginCtx")
+	args := []interface{}{login}
+	v, err :=  genericSelectUserCtx(ctx, db, selectUserByLoginStmt, args...)
+	return v, err
+}
+`
+	if got := buf.String(); got != want {
+		t.Errorf("Wanted context function %s, got %s", want, got)
+	}
+
+	buf.Reset()
+	fmt.Fprintf(newAPIWriter(&buf, contextForm), sCount, "User", "", "selectUserCountStmt")
+	if !bytes.Contains(buf.Bytes(), []byte("row := db.QueryRowContext(ctx, selectUserCountStmt)")) {
+		t.Errorf("Wanted count query with context, got %s", buf.String())
+	}
+
+	buf.Reset()
+	fmt.Fprintf(newAPIWriter(&buf, legacyForm), sCount, "User", "", "selectUserCountStmt")
+	if !bytes.Contains(buf.Bytes(), []byte("func CountUser(db db.SimpleDB)")) || !bytes.Contains(buf.Bytes(), []byte("row := db.QueryRow(selectUserCountStmt)")) {
+		t.Errorf("Wanted legacy count query, got %s", buf.String())
+	}
+}
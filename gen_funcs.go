@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
 
 	"bitbucket.org/pkg/inflect"
@@ -12,7 +13,7 @@ import (
 	"github.com/linchunquan/sqlgen/schema"
 )
 
-func writeImports(w io.Writer, tree *parse.Node, pkgs ...string) {
+func writeImports(w io.Writer, trees []*parse.Node, pkgs ...string) {
 	var pmap = map[string]struct{}{}
 
 	// add default packages
@@ -23,17 +24,33 @@ func writeImports(w io.Writer, tree *parse.Node, pkgs ...string) {
 	// check each edge node to see if it is
 	// encoded, which might require us to import
 	// other packages
-	for _, node := range tree.Edges() {
-		if node.Tags == nil || len(node.Tags.Encode) == 0 {
-			continue
-		}
-		switch node.Tags.Encode {
-		case "json":
-			pmap["encoding/json"] = struct{}{}
-			// case "gzip":
-			// 	pmap["compress/gzip"] = struct{}{}
-			// case "snappy":
-			// 	pmap["github.com/golang/snappy"] = struct{}{}
+	for _, tree := range trees {
+		// pointers to structs, value types and named types
+		// declared in another package are referenced by the
+		// generated scan functions.
+		tree.Walk(func(node *parse.Node) {
+			if (node.Kind == parse.Ptr || node.Kind == parse.Valuer || node.Named != "") && node.Import != tree.Import {
+				pmap[node.Import] = struct{}{}
+			}
+		})
+
+		for _, node := range tree.Edges() {
+			// time.Time is also named by the parameters
+			// of the finders and queries of *time.Time fields.
+			if node.Kind == parse.Time {
+				pmap["time"] = struct{}{}
+			}
+			if node.Tags == nil || len(node.Tags.Encode) == 0 {
+				continue
+			}
+			switch node.Tags.Encode {
+			case "json":
+				pmap["encoding/json"] = struct{}{}
+				// case "gzip":
+				// 	pmap["compress/gzip"] = struct{}{}
+				// case "snappy":
+				// 	pmap["github.com/golang/snappy"] = struct{}{}
+			}
 		}
 	}
 
@@ -41,10 +58,18 @@ func writeImports(w io.Writer, tree *parse.Node, pkgs ...string) {
 		return
 	}
 
+	// sort the packages so the output is stable
+	// across runs.
+	var sorted []string
+	for pkg := range pmap {
+		sorted = append(sorted, pkg)
+	}
+	sort.Strings(sorted)
+
 	// write the import block, including each
 	// encoder package that was specified.
 	fmt.Fprintln(w, "\nimport (")
-	for pkg, _ := range pmap {
+	for _, pkg := range sorted {
 		fmt.Fprintf(w, "\t%q\n", pkg)
 	}
 	fmt.Fprintln(w, ")")
@@ -63,9 +88,15 @@ func writeSliceFunc(srcPkgNameInShort string, w io.Writer, tree *parse.Node) {
 		}
 
 		// temporary variable declaration
-		switch node.Kind {
-		case parse.Map, parse.Slice:
+		switch {
+		case node.Kind == parse.Map, node.Kind == parse.Slice:
 			fmt.Fprintf(&buf1, "var v%d %s\n", i, "[]byte")
+		case node.Pointer:
+			// left as a nil interface when the pointer is
+			// nil so the driver writes NULL.
+			fmt.Fprintf(&buf1, "var v%d %s\n", i, "interface{}")
+		case node.Kind == parse.Valuer:
+			fmt.Fprintf(&buf1, "var v%d %s\n", i, getValuerType(srcPkgNameInShort, tree, node))
 		default:
 			fmt.Fprintf(&buf1, "var v%d %s\n", i, node.Type)
 		}
@@ -98,9 +129,19 @@ func writeSliceFunc(srcPkgNameInShort string, w io.Writer, tree *parse.Node) {
 			fmt.Fprintf(&buf2, "if v.%s != nil {\n", join(path[:len(path)-1], "."))
 		}
 
-		switch node.Kind {
-		case parse.Map, parse.Slice, parse.Struct, parse.Ptr:
+		switch {
+		case node.Kind == parse.Map, node.Kind == parse.Slice, node.Kind == parse.Struct, node.Kind == parse.Ptr:
 			fmt.Fprintf(&buf2, "v%d, _ = json.Marshal(&v.%s)\n", i, join(path, "."))
+		case node.Pointer && node.Kind == parse.Valuer:
+			// the pointer is passed on as-is so that Value
+			// may be declared on either receiver.
+			fmt.Fprintf(&buf2, "if v.%s != nil {\nv%d=v.%s\n}\n", join(path, "."), i, join(path, "."))
+		case node.Pointer && node.Named != "":
+			fmt.Fprintf(&buf2, "if v.%s != nil {\nv%d=%s(*v.%s)\n}\n", join(path, "."), i, node.Type, join(path, "."))
+		case node.Pointer:
+			fmt.Fprintf(&buf2, "if v.%s != nil {\nv%d=*v.%s\n}\n", join(path, "."), i, join(path, "."))
+		case node.Named != "":
+			fmt.Fprintf(&buf2, "v%d=%s(v.%s)\n", i, node.Type, join(path, "."))
 		default:
 			fmt.Fprintf(&buf2, "v%d=v.%s\n", i, join(path, "."))
 		}
@@ -125,26 +166,34 @@ func writeSliceFunc(srcPkgNameInShort string, w io.Writer, tree *parse.Node) {
 }
 
 func getAssignmentCode(buf *bytes.Buffer, node *parse.Node, i int, attr string) {
-	tmp := `
-    if v%d.Valid{
-        v.%s=v%d.%s
-    }else{
-        v.%s=%s
-    }
-`
-	if node.Type == "int" {
-		tmp = `
-    if v%d.Valid{
-        v.%s=int(v%d.%s64)
-    }else{
-        v.%s=%s
-    }
-`
+	// NOT NULL columns are scanned into the field type
+	// itself, so there is no NULL branch to handle.
+	if isNotNull(node) {
+		if node.Named != "" {
+			fmt.Fprintf(buf, "v.%s=%s(v%d)\n", attr, getParamType(node), i)
+		} else {
+			fmt.Fprintf(buf, "v.%s=v%d\n", attr, i)
+		}
+		return
+	}
+
+	value := fmt.Sprintf("v%d.%s", i, strings.Title(node.Type))
+	switch getSqlNullType(node) {
+	case "sql.NullInt64":
+		if node.Type != "int64" {
+			value = fmt.Sprintf("%s(v%d.Int64)", node.Type, i)
+		}
+	case "sql.NullFloat64":
+		if node.Type != "float64" {
+			value = fmt.Sprintf("%s(v%d.Float64)", node.Type, i)
+		}
 	}
 
-	value := strings.Title(node.Type)
 	defautlVal := `""`
-	if strings.Contains(node.Type, "bool") {
+	if node.Kind == parse.Time {
+		defautlVal = "time.Time{}"
+		value = fmt.Sprintf("v%d.Time", i)
+	} else if strings.Contains(node.Type, "bool") {
 		defautlVal = "false"
 	} else if strings.Contains(node.Type, "float") {
 		defautlVal = "0"
@@ -152,15 +201,83 @@ func getAssignmentCode(buf *bytes.Buffer, node *parse.Node, i int, attr string)
 		defautlVal = "0"
 	} else if strings.Contains(node.Type, "[]byte") {
 		defautlVal = "nil"
-		value = "Bytes"
+		value = fmt.Sprintf("v%d.Bytes", i)
 	}
 
-	fmt.Fprintf(buf, tmp, i, attr, i, value, attr, defautlVal)
+	// named types are converted from their underlying
+	// type, such as State(v5.String).
+	if node.Named != "" {
+		value = fmt.Sprintf("%s(%s)", getParamType(node), value)
+	}
+
+	// pointer fields keep NULL as nil so callers can tell
+	// an unset value apart from the zero value.
+	if node.Pointer {
+		fmt.Fprintf(buf, `
+    if v%d.Valid{
+        t%d:=%s
+        v.%s=&t%d
+    }else{
+        v.%s=nil
+    }
+`, i, i, value, attr, i, attr)
+		return
+	}
+
+	fmt.Fprintf(buf, `
+    if v%d.Valid{
+        v.%s=%s
+    }else{
+        v.%s=%s
+    }
+`, i, attr, value, attr, defautlVal)
+}
+
+// getScanType returns the Go type of the temporary
+// variable a column is scanned into.
+func getScanType(srcPkgNameInShort string, tree, node *parse.Node) string {
+	switch {
+	case node.Kind == parse.Map, node.Kind == parse.Slice:
+		return "[]byte"
+	case node.Kind == parse.Valuer:
+		// scanned directly, the type handles NULL
+		// in its own Scan method.
+		return getValuerType(srcPkgNameInShort, tree, node)
+	case isNotNull(node):
+		return node.Type
+	default:
+		return getSqlNullType(node)
+	}
+}
+
+// isNotNull returns true if the column of a non-pointer
+// field is declared NOT NULL.
+func isNotNull(node *parse.Node) bool {
+	return node.Tags != nil && node.Tags.NotNull && !node.Pointer
+}
+
+// getValuerType returns the Go type used to scan and
+// write a sql.Scanner and driver.Valuer field.
+func getValuerType(srcPkgNameInShort string, tree, node *parse.Node) string {
+	if node.Pointer {
+		return "*" + qualify(srcPkgNameInShort, tree, node)
+	}
+	return qualify(srcPkgNameInShort, tree, node)
 }
 
 func getSqlNullType(node *parse.Node) string{
-	if node.Type == "int" {
+	// database/sql has no null types for the smaller
+	// and unsigned integers, they are scanned through
+	// the widest type and converted.
+	switch node.Type {
+	case "int", "int8", "uint", "uint8", "uint16", "uint32", "uint64":
 		return "sql.NullInt64"
+	case "float32":
+		return "sql.NullFloat64"
+	}
+
+	if node.Kind == parse.Time {
+		return "sql.NullTime"
 	} else if node.Type == "[]byte" {
 		return "db.NullBytes"
 	}
@@ -178,12 +295,7 @@ func writeRowFunc(srcPkgNameInShort string, w io.Writer, tree *parse.Node) {
 		}
 
 		// temporary variable declaration
-		switch node.Kind {
-		case parse.Map, parse.Slice:
-			fmt.Fprintf(&buf1, "var v%d %s\n", i, "[]byte")
-		default:
-			fmt.Fprintf(&buf1, "var v%d %s\n", i, getSqlNullType(node))
-		}
+		fmt.Fprintf(&buf1, "var v%d %s\n", i, getScanType(srcPkgNameInShort, tree, node))
 
 		// variable scanning
 		fmt.Fprintf(&buf2, "&v%d,\n", i)
@@ -194,12 +306,14 @@ func writeRowFunc(srcPkgNameInShort string, w io.Writer, tree *parse.Node) {
 		// if the parent is a ptr struct we
 		// need to create a new
 		if parent != node.Parent && node.Parent.Kind == parse.Ptr {
-			fmt.Fprintf(&buf3, "v.%s=&%s{}\n", join(path[:len(path)-1], "."), node.Parent.Type)
+			fmt.Fprintf(&buf3, "v.%s=&%s{}\n", join(path[:len(path)-1], "."), qualify(srcPkgNameInShort, tree, node.Parent))
 		}
 
 		switch node.Kind {
 		case parse.Map, parse.Slice, parse.Struct, parse.Ptr:
 			fmt.Fprintf(&buf3, "json.Unmarshal(v%d, &v.%s)\n", i, join(path, "."))
+		case parse.Valuer:
+			fmt.Fprintf(&buf3, "v.%s=v%d\n", join(path, "."), i)
 		default:
 			//fmt.Fprintf(&buf3, "v.%s=v%d\n", join(path, "."), i)
 			getAssignmentCode(&buf3, node, i, join(path, "."))
@@ -208,6 +322,9 @@ func writeRowFunc(srcPkgNameInShort string, w io.Writer, tree *parse.Node) {
 		parent = node.Parent
 		i++
 	}
+	if tree.HasHook(parse.AfterScan) {
+		buf3.WriteString("if err := v.AfterScan(); err != nil {\nreturn nil, err\n}\n")
+	}
 	//fmt.Printf("tree.Type:%v",tree.Type)
 	fmt.Fprintf(w,
 		sScanRow,
@@ -232,12 +349,7 @@ func writeRowsFunc(srcPkgNameInShort string, w io.Writer, tree *parse.Node) {
 		}
 
 		// temporary variable declaration
-		switch node.Kind {
-		case parse.Map, parse.Slice:
-			fmt.Fprintf(&buf1, "var v%d %s\n", i, "[]byte")
-		default:
-			fmt.Fprintf(&buf1, "var v%d %s\n", i, getSqlNullType(node))
-		}
+		fmt.Fprintf(&buf1, "var v%d %s\n", i, getScanType(srcPkgNameInShort, tree, node))
 
 		// variable scanning
 		fmt.Fprintf(&buf2, "&v%d,\n", i)
@@ -248,12 +360,14 @@ func writeRowsFunc(srcPkgNameInShort string, w io.Writer, tree *parse.Node) {
 		// if the parent is a ptr struct we
 		// need to create a new
 		if parent != node.Parent && node.Parent.Kind == parse.Ptr {
-			fmt.Fprintf(&buf3, "v.%s=&%s{}\n", join(path[:len(path)-1], "."), node.Parent.Type)
+			fmt.Fprintf(&buf3, "v.%s=&%s{}\n", join(path[:len(path)-1], "."), qualify(srcPkgNameInShort, tree, node.Parent))
 		}
 
 		switch node.Kind {
 		case parse.Map, parse.Slice, parse.Struct, parse.Ptr:
 			fmt.Fprintf(&buf3, "json.Unmarshal(v%d, &v.%s)\n", i, join(path, "."))
+		case parse.Valuer:
+			fmt.Fprintf(&buf3, "v.%s=v%d\n", join(path, "."), i)
 		default:
 			//fmt.Fprintf(&buf3, "v.%s=v%d\n", join(path, "."), i)
 			getAssignmentCode(&buf3, node, i, join(path, "."))
@@ -262,6 +376,9 @@ func writeRowsFunc(srcPkgNameInShort string, w io.Writer, tree *parse.Node) {
 		parent = node.Parent
 		i++
 	}
+	if tree.HasHook(parse.AfterScan) {
+		buf3.WriteString("if err := v.AfterScan(); err != nil {\nreturn vv, err\n}\n")
+	}
 
 	fmt.Fprintf(w,
 		sScanRows,
@@ -294,8 +411,158 @@ func writeGenericUpdateFunc(srcPkgNameInShort string, w io.Writer, tree *parse.N
 	fmt.Fprintf(w, sGenericUpdate, tree.Type, srcPkgNameInShort+"."+tree.Type, tree.Type)
 }
 
-func writeInsertFunc(srcPkgNameInShort string, w io.Writer,  tree *parse.Node, t *schema.Table){
-	fmt.Fprintf(w, sInsert, tree.Type, srcPkgNameInShort+"."+tree.Type, getLabelName("insert", inflect.Singularize(t.Name), "stmt"), tree.Type)
+func writeInsertFunc(srcPkgNameInShort string, w io.Writer,  tree *parse.Node, t *schema.Table, d schema.Dialect){
+	// tables without an auto-increment column, such as
+	// join tables keyed by a composite primary key, insert
+	// every column and have no generated key to read back.
+	auto := getAuto(t)
+	if auto == nil {
+		fmt.Fprintf(w, sInsertNoAuto, tree.Type, srcPkgNameInShort+"."+tree.Type, getWrapCode(tree, "Insert"+tree.Type), getBeforeCode(tree, t, true), getLabelName("insert", inflect.Singularize(t.Name), "stmt"), tree.Type, getAfterInsertCode(tree))
+		return
+	}
+
+	// the slice function returns a value for each field
+	// of the table, pick the same non-auto fields as the
+	// dialect's INSERT statement.
+	var args []string
+	for i, field := range t.Fields {
+		if !field.Auto {
+			args = append(args, fmt.Sprintf("args[%d]", i))
+		}
+	}
+
+	if d.Returning() {
+		var dest []string
+		for _, field := range t.Fields {
+			if field.Auto {
+				dest = append(dest, "&v."+join(field.Node.Path()[1:], "."))
+			}
+		}
+		fmt.Fprintf(w, sInsertReturning,
+			tree.Type,
+			srcPkgNameInShort+"."+tree.Type,
+			getWrapCode(tree, "Insert"+tree.Type),
+			getBeforeCode(tree, t, true),
+			tree.Type,
+			getLabelName("insert", inflect.Singularize(t.Name), "stmt"),
+			strings.Join(args, ", "),
+			strings.Join(dest, ", "),
+			getAfterInsertCode(tree))
+		return
+	}
+
+	fmt.Fprintf(w, sInsert,
+		tree.Type,
+		srcPkgNameInShort+"."+tree.Type,
+		getWrapCode(tree, "Insert"+tree.Type),
+		getBeforeCode(tree, t, true),
+		tree.Type,
+		getLabelName("insert", inflect.Singularize(t.Name), "stmt"),
+		strings.Join(args, ", "),
+		join(auto.Node.Path()[1:], "."),
+		getIdConversion(auto.Node, "id"),
+		getAfterInsertCode(tree))
+}
+
+// writeInsertBatchQuery writes the function building the
+// multi-row INSERT statement used by the batch insert.
+func writeInsertBatchQuery(w io.Writer, tree *parse.Node, t *schema.Table, d schema.Dialect) {
+	var cols int
+	var returning []string
+	for _, field := range t.Fields {
+		if field.Auto {
+			returning = append(returning, field.Name)
+		} else {
+			cols++
+		}
+	}
+	if cols == 0 {
+		return
+	}
+
+	var suffix string
+	if d.Returning() && len(returning) != 0 {
+		suffix = fmt.Sprintf(" + %q", "\nRETURNING "+strings.Join(returning, ","))
+	}
+
+	fmt.Fprintf(w, sInsertBatchQuery,
+		tree.Type,
+		getLabelName("insert", inflect.Singularize(t.Name), "batch", "stmt"),
+		cols,
+		d.Runtime(),
+		suffix)
+}
+
+// writeInsertBatchFunc writes the function inserting a
+// slice of values with multi-row INSERT statements.
+func writeInsertBatchFunc(srcPkgNameInShort string, w io.Writer, tree *parse.Node, t *schema.Table, d schema.Dialect) {
+	var args, dest []string
+	for i, field := range t.Fields {
+		if field.Auto {
+			dest = append(dest, "&v."+join(field.Node.Path()[1:], "."))
+		} else {
+			args = append(args, fmt.Sprintf("a[%d]", i))
+		}
+	}
+	if len(args) == 0 {
+		return
+	}
+
+	// each chunk holds as many rows as fit within the
+	// dialect's limit on bind parameters.
+	size := d.MaxParams() / len(args)
+
+	var after string
+	if tree.HasHook(parse.AfterInsert) {
+		after = "for _, v := range vv[:n] {\n" + getAfterInsertCode(tree) + "}\n"
+	}
+
+	// the generated keys are read with RETURNING, or follow
+	// from the LastInsertId of each chunk, as the keys of a
+	// single multi-row insert are consecutive.
+	exec := fmt.Sprintf(sInsertBatchExec, tree.Type)
+	if auto := getAuto(t); d.Returning() && len(dest) != 0 {
+		exec = fmt.Sprintf(sInsertBatchReturning, tree.Type, strings.Join(dest, ", "))
+	} else if auto != nil {
+		var first string
+		if !d.FirstInsertId() {
+			first = "\t\tid -= int64(n - 1)\n"
+		}
+		exec = fmt.Sprintf(sInsertBatchLastId, tree.Type, first, join(auto.Node.Path()[1:], "."), getIdConversion(auto.Node, "id"))
+	}
+
+	plural := inflections.Pluralize(tree.Type)
+	typ := srcPkgNameInShort + "." + tree.Type
+	fmt.Fprintf(w, sInsertBatch,
+		plural,
+		typ,
+		getWrapCode(tree, "Insert"+plural),
+		size,
+		plural,
+		plural,
+		plural,
+		typ,
+		plural,
+		plural,
+		typ,
+		size,
+		size,
+		len(args),
+		getBeforeCode(tree, t, true),
+		tree.Type,
+		strings.Join(args, ", "),
+		exec,
+		after)
+}
+
+// getIdConversion returns the expression converting the
+// int64 generated key to the type of the auto field.
+func getIdConversion(node *parse.Node, id string) string {
+	typ := getParamType(node)
+	if typ == "int64" {
+		return id
+	}
+	return fmt.Sprintf("%s(%s)", typ, id)
 }
 
 func writeDeleteFunc(srcPkgNameInShort string, w io.Writer,  tree *parse.Node, t *schema.Table){
@@ -304,6 +571,7 @@ func writeDeleteFunc(srcPkgNameInShort string, w io.Writer,  tree *parse.Node, t
 			tree.Type,
 			getLabelName("by", joinField(t.Primary, "And")),
 			joinObjectFieldInDetails(t.Primary, ",", true),
+			getWrapCode(tree, "Delete"+tree.Type+getLabelName("by", joinField(t.Primary, "And"))),
 			joinObjectFieldInDetails(t.Primary, ",", false),
 			getLabelName("delete", inflect.Singularize(t.Name), "by", joinField(t.Primary, "And"), "stmt"))
 	}
@@ -314,6 +582,7 @@ func writeDeleteFunc(srcPkgNameInShort string, w io.Writer,  tree *parse.Node, t
 					tree.Type,
 					getLabelName("by", joinField(ix.Fields, "And")),
 					joinObjectFieldInDetails(ix.Fields, ",", true),
+					getWrapCode(tree, "Delete"+tree.Type+getLabelName("by", joinField(ix.Fields, "And"))),
 					joinObjectFieldInDetails(ix.Fields, ",", false),
 					getLabelName("delete", inflect.Singularize(t.Name), "by", joinField(ix.Fields, "And"), "stmt"))
 			//}
@@ -321,12 +590,75 @@ func writeDeleteFunc(srcPkgNameInShort string, w io.Writer,  tree *parse.Node, t
 	}
 }
 
+// writeWithDeletedFuncs writes the functions of a table
+// with a soft delete field that find, get and count the rows
+// marked as deleted along with the others, and the functions
+// deleting rows physically.
+func writeWithDeletedFuncs(srcPkgNameInShort string, w io.Writer, tree *parse.Node, t *schema.Table) {
+	if schema.SoftDeleteField(t) == nil {
+		return
+	}
+
+	fmt.Fprintf(w, sFindAllWithDeleted,
+		tree.Type,
+		srcPkgNameInShort+"."+tree.Type,
+		getWrapCode(tree, "FindAll"+tree.Type+"sWithDeleted"),
+		tree.Type,
+		getLabelName("select", inflect.Singularize(t.Name), "with", "deleted", "stmt"))
+	fmt.Fprintf(w, sCount,
+		tree.Type+"WithDeleted",
+		getWrapCode(tree, "Count"+tree.Type+"WithDeleted"),
+		getLabelName("select", inflect.Singularize(t.Name), "count", "with", "deleted", "stmt"))
+
+	var keys, deletes [][]*schema.Field
+	if len(t.Primary) != 0 {
+		keys = append(keys, t.Primary)
+		deletes = append(deletes, t.Primary)
+	}
+	for _, ix := range t.Index {
+		if ix.Unique {
+			keys = append(keys, ix.Fields)
+		}
+		deletes = append(deletes, ix.Fields)
+	}
+
+	for _, fields := range keys {
+		fmt.Fprintf(w, sGetBy,
+			tree.Type,
+			getLabelName("by", joinField(fields, "And"))+"WithDeleted",
+			joinObjectFieldInDetails(fields, ",", true),
+			srcPkgNameInShort+"."+tree.Type,
+			getWrapCode(tree, "Get"+tree.Type+getLabelName("by", joinField(fields, "And"))+"WithDeleted"),
+			joinObjectFieldInDetails(fields, ",", false),
+			tree.Type,
+			getLabelName("select", inflect.Singularize(t.Name), "by", joinField(fields, "And"), "with", "deleted", "stmt"))
+	}
+	if *view {
+		return
+	}
+	for _, fields := range deletes {
+		fmt.Fprintf(w, sHardDelete,
+			tree.Type,
+			getLabelName("by", joinField(fields, "And")),
+			joinObjectFieldInDetails(fields, ",", true),
+			getWrapCode(tree, "HardDelete"+tree.Type+getLabelName("by", joinField(fields, "And"))),
+			joinObjectFieldInDetails(fields, ",", false),
+			getLabelName("hard", "delete", inflect.Singularize(t.Name), "by", joinField(fields, "And"), "stmt"))
+	}
+}
+
 func writeUpdateFunc(srcPkgNameInShort string, w io.Writer,  tree *parse.Node, t *schema.Table){
+	if !*updateKeys || schema.VersionField(t) != nil {
+		writeUpdateSetFunc(srcPkgNameInShort, w, tree, t)
+		return
+	}
 	if len(t.Primary) !=0 {
 		fmt.Fprintf(w, sUpdate,
 			tree.Type,
 			getLabelName("by", joinField(t.Primary, "And")),
 			srcPkgNameInShort+"."+tree.Type,
+			getWrapCode(tree, "Update"+tree.Type+getLabelName("by", joinField(t.Primary, "And"))),
+			getBeforeCode(tree, t, false),
 			tree.Type,
 			joinObjectField(t.Primary, ","),
 			getLabelName("update", inflect.Singularize(t.Name), "by", joinField(t.Primary, "And"), "stmt"))
@@ -338,6 +670,8 @@ func writeUpdateFunc(srcPkgNameInShort string, w io.Writer,  tree *parse.Node, t
 					tree.Type,
 					getLabelName("by", joinField(ix.Fields, "And")),
 					srcPkgNameInShort+"."+tree.Type,
+					getWrapCode(tree, "Update"+tree.Type+getLabelName("by", joinField(ix.Fields, "And"))),
+					getBeforeCode(tree, t, false),
 					tree.Type,
 					joinObjectField(ix.Fields, ","),
 					getLabelName("update", inflect.Singularize(t.Name), "by", joinField(ix.Fields, "And"), "stmt"))
@@ -346,6 +680,310 @@ func writeUpdateFunc(srcPkgNameInShort string, w io.Writer,  tree *parse.Node, t
 	}
 }
 
+// writeUpdateSetFunc writes the update functions for the
+// primary key and each unique index, passing the values of
+// the set fields, as chosen by updateFields, and for a table
+// with a version column the version read.
+func writeUpdateSetFunc(srcPkgNameInShort string, w io.Writer, tree *parse.Node, t *schema.Table) {
+	set := updateFields(t)
+	if len(set) == 0 {
+		return
+	}
+	version := schema.VersionField(t)
+
+	var keys [][]*schema.Field
+	if len(t.Primary) != 0 {
+		keys = append(keys, t.Primary)
+	}
+	for _, ix := range t.Index {
+		if ix.Unique {
+			keys = append(keys, ix.Fields)
+		}
+	}
+
+	for _, fields := range keys {
+		// the version is incremented by the statement
+		// rather than set, and compared last.
+		var args []string
+		for _, field := range set {
+			if field != version {
+				args = append(args, fmt.Sprintf("a[%d]", fieldIndex(t, field)))
+			}
+		}
+		for _, field := range fields {
+			args = append(args, fmt.Sprintf("a[%d]", fieldIndex(t, field)))
+		}
+		if version == nil {
+			fmt.Fprintf(w, sUpdateSet,
+				tree.Type,
+				getLabelName("by", joinField(fields, "And")),
+				srcPkgNameInShort+"."+tree.Type,
+				getWrapCode(tree, "Update"+tree.Type+getLabelName("by", joinField(fields, "And"))),
+				getBeforeCode(tree, t, false),
+				tree.Type,
+				getLabelName("update", inflect.Singularize(t.Name), "by", joinField(fields, "And"), "stmt"),
+				strings.Join(args, ", "))
+			continue
+		}
+		args = append(args, fmt.Sprintf("a[%d]", fieldIndex(t, version)))
+		fmt.Fprintf(w, sUpdateVersion,
+			tree.Type,
+			getLabelName("by", joinField(fields, "And")),
+			srcPkgNameInShort+"."+tree.Type,
+			getWrapCode(tree, "Update"+tree.Type+getLabelName("by", joinField(fields, "And"))),
+			getBeforeCode(tree, t, false),
+			tree.Type,
+			getLabelName("update", inflect.Singularize(t.Name), "by", joinField(fields, "And"), "stmt"),
+			strings.Join(args, ", "),
+			inflect.CamelizeDownFirst(tree.Type),
+			join(version.Node.Path()[1:], "."))
+	}
+}
+
+// writeCheckVersion writes the function checking the
+// result of a versioned update of the table.
+func writeCheckVersion(w io.Writer, tree *parse.Node, t *schema.Table) {
+	if schema.VersionField(t) == nil {
+		return
+	}
+	name := inflect.CamelizeDownFirst(tree.Type)
+	fmt.Fprintf(w, sCheckVersion, name, t.Name, name, t.Name)
+}
+
+// writeUpdateFieldsQuery writes the function returning the
+// statement and parameters updating the listed columns of a
+// row, which needs the primary key to find the row. The
+// version of a versioned table is always set to the next
+// version, and compared with the version read, and the update
+// times are always set.
+func writeUpdateFieldsQuery(srcPkgNameInShort string, w io.Writer, tree *parse.Node, t *schema.Table, d schema.Dialect) {
+	if len(t.Primary) == 0 {
+		return
+	}
+
+	var keys, names []string
+	for _, field := range t.Primary {
+		keys = append(keys, fmt.Sprintf("a[%d]", fieldIndex(t, field)))
+		names = append(names, fmt.Sprintf("%q", field.Name))
+	}
+
+	var always []string
+	var next string
+	_, update := schema.AutoTimeFields(t)
+	for _, field := range update {
+		always = append(always, fmt.Sprintf("col == %sColumn%s", tree.Type, inflect.Camelize(field.Name[2:])))
+		next += fmt.Sprintf("\tnames = append(names, %q)\n\targs = append(args, a[%d])\n", field.Name, fieldIndex(t, field))
+	}
+	if version := schema.VersionField(t); version != nil {
+		always = append(always, fmt.Sprintf("col == %sColumn%s", tree.Type, inflect.Camelize(version.Name[2:])))
+		next += fmt.Sprintf("\tnames = append(names, %q)\n\targs = append(args, v.%s+1)\n", version.Name, join(version.Node.Path()[1:], "."))
+		keys = append(keys, fmt.Sprintf("a[%d]", fieldIndex(t, version)))
+		names = append(names, fmt.Sprintf("%q", version.Name))
+	}
+	name := inflect.CamelizeDownFirst(tree.Type)
+	var skip string
+	if cols := getKeyColumns(tree, t); len(cols) != 0 {
+		skip = fmt.Sprintf("\t\tswitch col {\n\t\tcase %s:\n\t\t\treturn \"\", nil, fmt.Errorf(\"cannot update %s key column %%s\", %sColumns[col])\n\t\t}\n",
+			strings.Join(cols, ", "), t.Name, name)
+	}
+	if len(always) != 0 {
+		skip += fmt.Sprintf("\t\tif %s {\n\t\t\tcontinue\n\t\t}\n", strings.Join(always, " || "))
+	}
+
+	fmt.Fprintf(w, sUpdateFieldsQuery,
+		name,
+		name, srcPkgNameInShort+"."+tree.Type, tree.Type,
+		tree.Type,
+		name,
+		t.Name,
+		skip,
+		name,
+		next,
+		strings.Join(keys, ", "),
+		t.Name, strings.Join(names, ", "), d.Runtime())
+}
+
+// getKeyColumns returns the columns of the primary key and
+// auto fields, which an update of the listed columns rejects
+// unless -updateKeys is true, as the other updates leave them
+// out of the SET list.
+func getKeyColumns(tree *parse.Node, t *schema.Table) []string {
+	if *updateKeys {
+		return nil
+	}
+	var cols []string
+	for _, field := range t.Fields {
+		if field.Primary || field.Auto {
+			cols = append(cols, tree.Type+"Column"+inflect.Camelize(field.Name[2:]))
+		}
+	}
+	return cols
+}
+
+// writeStampTime writes the functions setting the automatic
+// timestamps of a row before it is inserted or updated. The
+// creation times are kept if already set, so that rows may be
+// copied with their original times.
+func writeStampTime(srcPkgNameInShort string, w io.Writer, tree *parse.Node, t *schema.Table) {
+	create, update := schema.AutoTimeFields(t)
+	if len(create) == 0 && len(update) == 0 {
+		return
+	}
+
+	var insert bytes.Buffer
+	for _, field := range create {
+		path := join(field.Node.Path()[1:], ".")
+		fmt.Fprintf(&insert, "\tif v.%s.IsZero() {\n\t\tv.%s = now\n\t}\n", path, path)
+	}
+	var set bytes.Buffer
+	for _, field := range update {
+		fmt.Fprintf(&set, "\tv.%s = now\n", join(field.Node.Path()[1:], "."))
+	}
+
+	name := inflect.CamelizeDownFirst(tree.Type)
+	typ := srcPkgNameInShort + "." + tree.Type
+	fmt.Fprintf(w, sStampTime, name, "Insert", "inserted", name, "Insert", typ, insert.String()+set.String())
+	if len(update) != 0 {
+		fmt.Fprintf(w, sStampTime, name, "Update", "updated", name, "Update", typ, set.String())
+	}
+}
+
+// writeWrapError writes the function translating the
+// errors of the generated functions of a table and wrapping
+// them with the table and function name.
+func writeWrapError(w io.Writer, tree *parse.Node, t *schema.Table, d schema.Dialect) {
+	name := inflect.CamelizeDownFirst(tree.Type)
+	fmt.Fprintf(w, sWrapError, name, t.Name, name, t.Name, d.ErrorFunc())
+}
+
+// getWrapCode returns the statement deferring the
+// wrapping of the error of the generated function fn,
+// named with the suffix of the api it is written in.
+func getWrapCode(tree *parse.Node, fn string) string {
+	return fmt.Sprintf("defer %sWrapError(&err, %q)\n", inflect.CamelizeDownFirst(tree.Type), fn+"{{.Ctx}}")
+}
+
+// getBeforeCode returns the statements calling the Before
+// hook of v, whose error aborts the write, and then setting its
+// automatic timestamps, before it is inserted or updated.
+func getBeforeCode(tree *parse.Node, t *schema.Table, insert bool) string {
+	hook := parse.BeforeUpdate
+	if insert {
+		hook = parse.BeforeInsert
+	}
+	var code string
+	if tree.HasHook(hook) {
+		code = fmt.Sprintf("if err := v.%s(); err != nil {\nreturn err\n}\n", hook)
+	}
+	return code + getStampCode(tree, t, insert)
+}
+
+// getAfterInsertCode returns the statement calling the
+// AfterInsert hook of v, if the type has one.
+func getAfterInsertCode(tree *parse.Node) string {
+	if tree.HasHook(parse.AfterInsert) {
+		return "v." + parse.AfterInsert + "()\n"
+	}
+	return ""
+}
+
+// getStampCode returns the statement setting the automatic
+// timestamps of v before it is inserted or updated, if the
+// table has any.
+func getStampCode(tree *parse.Node, t *schema.Table, insert bool) string {
+	create, update := schema.AutoTimeFields(t)
+	if insert && len(create)+len(update) != 0 {
+		return inflect.CamelizeDownFirst(tree.Type) + "StampInsert(v)\n"
+	}
+	if !insert && len(update) != 0 {
+		return inflect.CamelizeDownFirst(tree.Type) + "StampUpdate(v)\n"
+	}
+	return ""
+}
+
+// writeUpdateFieldsFunc writes the function updating the
+// listed columns of a row by its primary key.
+func writeUpdateFieldsFunc(srcPkgNameInShort string, w io.Writer, tree *parse.Node, t *schema.Table) {
+	if len(t.Primary) == 0 {
+		return
+	}
+	if version := schema.VersionField(t); version != nil {
+		fmt.Fprintf(w, sUpdateFieldsVersion,
+			tree.Type,
+			srcPkgNameInShort+"."+tree.Type,
+			tree.Type,
+			getWrapCode(tree, "Update"+tree.Type+"Fields"),
+			getBeforeCode(tree, t, false),
+			inflect.CamelizeDownFirst(tree.Type),
+			inflect.CamelizeDownFirst(tree.Type),
+			join(version.Node.Path()[1:], "."))
+		return
+	}
+	fmt.Fprintf(w, sUpdateFields,
+		tree.Type,
+		srcPkgNameInShort+"."+tree.Type,
+		tree.Type,
+		getWrapCode(tree, "Update"+tree.Type+"Fields"),
+		getBeforeCode(tree, t, false),
+		inflect.CamelizeDownFirst(tree.Type))
+}
+
+// fieldIndex returns the index of the field in the table,
+// which is also its index in the values of the slice function.
+func fieldIndex(t *schema.Table, field *schema.Field) int {
+	for i, f := range t.Fields {
+		if f.Name == field.Name {
+			return i
+		}
+	}
+	return -1
+}
+
+// writeUpsertFunc writes an upsert function for each
+// key of the table an upsert may conflict on. The version
+// of a versioned row, which the upsert increments when the
+// row exists, is read back into v.
+func writeUpsertFunc(srcPkgNameInShort string, w io.Writer, tree *parse.Node, t *schema.Table, d schema.Dialect) {
+	version := schema.VersionField(t)
+	for _, fields := range schema.UpsertKeys(t) {
+		// pick the values of the inserted fields from
+		// the slice function, in the statement's order.
+		insert, _ := schema.UpsertFields(t, fields)
+		var args []string
+		for _, field := range insert {
+			args = append(args, fmt.Sprintf("a[%d]", fieldIndex(t, field)))
+		}
+
+		stmt := getLabelName("upsert", inflect.Singularize(t.Name), "by", joinField(fields, "And"), "stmt")
+		exec := fmt.Sprintf(sUpsertExec, stmt, strings.Join(args, ", "))
+		if version != nil {
+			path := join(version.Node.Path()[1:], ".")
+			if d.Returning() {
+				exec = fmt.Sprintf(sUpsertVersion, stmt, strings.Join(args, ", "), path)
+			} else {
+				var keys []string
+				for _, field := range fields {
+					keys = append(keys, fmt.Sprintf("a[%d]", fieldIndex(t, field)))
+				}
+				exec += fmt.Sprintf(sUpsertVersion,
+					getLabelName("select", inflect.Singularize(t.Name), "version", "by", joinField(fields, "And"), "stmt"),
+					strings.Join(keys, ", "),
+					path)
+			}
+		}
+
+		fmt.Fprintf(w, sUpsert,
+			tree.Type,
+			getLabelName("by", joinField(fields, "And")),
+			srcPkgNameInShort+"."+tree.Type,
+			getWrapCode(tree, "Upsert"+tree.Type+getLabelName("by", joinField(fields, "And"))),
+			getBeforeCode(tree, t, true),
+			tree.Type,
+			exec,
+			getAfterInsertCode(tree))
+	}
+}
+
 func writeGetByFunc(srcPkgNameInShort string, w io.Writer,  tree *parse.Node, t *schema.Table){
 	if len(t.Primary) !=0 {
 		fmt.Fprintf(w, sGetBy,
@@ -353,6 +991,7 @@ func writeGetByFunc(srcPkgNameInShort string, w io.Writer,  tree *parse.Node, t
 			getLabelName("by", joinField(t.Primary, "And")),
 			joinObjectFieldInDetails(t.Primary, ",", true),
 			srcPkgNameInShort+"."+tree.Type,
+			getWrapCode(tree, "Get"+tree.Type+getLabelName("by", joinField(t.Primary, "And"))),
 			joinObjectFieldInDetails(t.Primary, ",", false),
 			tree.Type,
 			getLabelName("select", inflect.Singularize(t.Name), "by", joinField(t.Primary, "And"), "stmt"))
@@ -365,6 +1004,7 @@ func writeGetByFunc(srcPkgNameInShort string, w io.Writer,  tree *parse.Node, t
 					getLabelName("by", joinField(ix.Fields, "And")),
 					joinObjectFieldInDetails(ix.Fields, ",", true),
 					srcPkgNameInShort+"."+tree.Type,
+					getWrapCode(tree, "Get"+tree.Type+getLabelName("by", joinField(ix.Fields, "And"))),
 					joinObjectFieldInDetails(ix.Fields, ",", false),
 					tree.Type,
 					getLabelName("select", inflect.Singularize(t.Name), "by", joinField(ix.Fields, "And"), "stmt"))
@@ -382,6 +1022,7 @@ func writeFindByIndexFunc(srcPkgNameInShort string, w io.Writer,  tree *parse.No
 					getLabelName("by", joinField(ix.Fields, "And")),
 					joinObjectFieldInDetails(ix.Fields, ",", true),
 					srcPkgNameInShort+"."+tree.Type,
+					getWrapCode(tree, "Find"+tree.Type+"s"+getLabelName("by", joinField(ix.Fields, "And"))),
 					joinObjectFieldInDetails(ix.Fields, ",", false),
 					tree.Type,
 					getLabelName("select", inflect.Singularize(t.Name), "by", joinField(ix.Fields, "And"), "stmt"))
@@ -391,6 +1032,7 @@ func writeFindByIndexFunc(srcPkgNameInShort string, w io.Writer,  tree *parse.No
 					getLabelName("by", joinField(ix.Fields, "And")),
 					joinObjectFieldInDetails(ix.Fields, ",", true),
 					srcPkgNameInShort+"."+tree.Type,
+					getWrapCode(tree, "Find"+tree.Type+"s"+getLabelName("by", joinField(ix.Fields, "And"))+"InRange"),
 					joinObjectFieldInDetails(ix.Fields, ",", false),
 					tree.Type,
 					getLabelName("select", inflect.Singularize(t.Name), "range", "by", joinField(ix.Fields, "And"), "stmt"))
@@ -409,6 +1051,7 @@ func writeFindByForeignKeyFunc(srcPkgNameInShort string, w io.Writer,  tree *par
 					getLabelName("by", joinField(fk.FromFields, "And")),
 					joinObjectFieldInDetails(fk.FromFields, ",", true),
 					srcPkgNameInShort+"."+tree.Type,
+					getWrapCode(tree, "Find"+tree.Type+"sOf"+inflect.Camelize(fk.ToTable[:len(fk.ToTable)-1])+getLabelName("by", joinField(fk.FromFields, "And"))),
 					joinObjectFieldInDetails(fk.FromFields, ",", false),
 					tree.Type,
 					getLabelName("select", inflect.Singularize(t.Name), "of", inflect.Singularize(fk.ToTable), "by", joinColumnNames(fk.FromColumns, "And"), "stmt"))
@@ -419,6 +1062,7 @@ func writeFindByForeignKeyFunc(srcPkgNameInShort string, w io.Writer,  tree *par
 					getLabelName("by", joinField(fk.FromFields, "And")),
 					joinObjectFieldInDetails(fk.FromFields, ",", true),
 					srcPkgNameInShort+"."+tree.Type,
+					getWrapCode(tree, "Find"+tree.Type+"sOf"+inflect.Camelize(fk.ToTable[:len(fk.ToTable)-1])+getLabelName("by", joinField(fk.FromFields, "And"))+"InRange"),
 					joinObjectFieldInDetails(fk.FromFields, ",", false),
 					tree.Type,
 					getLabelName("select", inflect.Singularize(t.Name), "of", inflect.Singularize(fk.ToTable), "range", "by", joinField(fk.FromFields, "And"), "stmt"))
@@ -429,6 +1073,7 @@ func writeFindByForeignKeyFunc(srcPkgNameInShort string, w io.Writer,  tree *par
 					getLabelName("by", joinField(fk.FromFields, "And")),
 					joinObjectFieldInDetails(fk.FromFields, ",", true),
 					srcPkgNameInShort+"."+tree.Type,
+					getWrapCode(tree, "Get"+tree.Type+"Of"+inflect.Camelize(fk.ToTable[:len(fk.ToTable)-1])+getLabelName("by", joinField(fk.FromFields, "And"))),
 					joinObjectFieldInDetails(fk.FromFields, ",", false),
 					tree.Type,
 					getLabelName("select", inflect.Singularize(t.Name), "of", inflect.Singularize(fk.ToTable), "by", joinColumnNames(fk.FromColumns, "And"), "stmt"))
@@ -437,16 +1082,265 @@ func writeFindByForeignKeyFunc(srcPkgNameInShort string, w io.Writer,  tree *par
 	}
 }
 
+// writeCursorFuncs writes the functions encoding and
+// decoding the primary key of a row as a page cursor.
+func writeCursorFuncs(srcPkgNameInShort string, w io.Writer, tree *parse.Node, t *schema.Table) {
+	if len(t.Primary) == 0 {
+		return
+	}
+
+	var decls bytes.Buffer
+	var keys, dest, args []string
+	for i, field := range t.Primary {
+		keys = append(keys, "v."+join(field.Node.Path()[1:], "."))
+		fmt.Fprintf(&decls, "\tvar k%d %s\n", i, getParamType(field.Node))
+		dest = append(dest, fmt.Sprintf("&k%d", i))
+		args = append(args, fmt.Sprintf("k%d", i))
+	}
+
+	name := inflect.CamelizeDownFirst(tree.Type)
+	fmt.Fprintf(w, sCursor,
+		name,
+		srcPkgNameInShort+"."+tree.Type,
+		strings.Join(keys, ", "),
+		name,
+		decls.String(),
+		strings.Join(dest, ", "),
+		strings.Join(args, ", "))
+}
+
+// writeColumns writes the enum of the table's columns
+// accepted by the sorted finders.
+func writeColumns(w io.Writer, tree *parse.Node, t *schema.Table) {
+	var consts bytes.Buffer
+	var names []string
+	for i, field := range t.Fields {
+		if i == 0 {
+			fmt.Fprintf(&consts, "\t%sColumn%s %sColumn = iota\n", tree.Type, inflect.Camelize(field.Name[2:]), tree.Type)
+		} else {
+			fmt.Fprintf(&consts, "\t%sColumn%s\n", tree.Type, inflect.Camelize(field.Name[2:]))
+		}
+		names = append(names, fmt.Sprintf("%q", field.Name))
+	}
+
+	// the rows of equal columns are sorted by the primary
+	// key, as by the in-memory store.
+	var key string
+	for _, field := range t.Primary {
+		key += "," + field.Name
+	}
+	if key != "" {
+		key = fmt.Sprintf(" + %q", key)
+	}
+
+	name := inflect.CamelizeDownFirst(tree.Type)
+	fmt.Fprintf(w, sColumns,
+		tree.Type, t.Name,
+		tree.Type,
+		consts.String(),
+		name, strings.Join(names, ", "),
+		name,
+		name, tree.Type,
+		name,
+		t.Name,
+		name, key)
+}
+
+// writeQuery writes the query builder of the table, with
+// a Where and OrderBy method for each column that is not
+// encoded as json.
+func writeQuery(w io.Writer, tree *parse.Node, t *schema.Table, d schema.Dialect) {
+	var order []string
+	for _, field := range schema.OrderFields(t) {
+		if field.Order == "DESC" {
+			order = append(order, fmt.Sprintf(", %q", field.Name+" DESC"))
+		} else {
+			order = append(order, fmt.Sprintf(", %q", field.Name))
+		}
+	}
+
+	// the query of a table with a soft delete field
+	// excludes the rows marked as deleted, as the other
+	// selects do, unless built with the WithDeleted variant.
+	var live string
+	if field := schema.SoftDeleteField(t); field != nil {
+		live = fmt.Sprintf(".Where(%q, db.IsNull)", field.Name)
+		if field.Type == schema.BOOLEAN {
+			live = fmt.Sprintf(".Where(%q, db.Eq, false)", field.Name)
+		}
+	}
+
+	// the primary key breaks the ties of the sorted
+	// columns, as it does those of the default order.
+	key := "nil"
+	if len(t.Primary) != 0 {
+		var names []string
+		for _, field := range t.Primary {
+			names = append(names, fmt.Sprintf("%q", field.Name))
+		}
+		key = "[]string{" + strings.Join(names, ", ") + "}"
+	}
+
+	stmt := getLabelName("select", inflect.Singularize(t.Name), "query", "stmt")
+	fmt.Fprintf(w, sQuery,
+		tree.Type, t.Name,
+		tree.Type,
+		tree.Type, t.Name,
+		tree.Type, tree.Type,
+		tree.Type,
+		stmt,
+		d.Runtime(),
+		key,
+		strings.Join(order, ""),
+		live,
+		tree.Type, tree.Type,
+		tree.Type, tree.Type,
+		tree.Type)
+
+	if live != "" {
+		fmt.Fprintf(w, sQueryWithDeleted,
+			tree.Type, t.Name,
+			tree.Type, tree.Type,
+			tree.Type,
+			stmt,
+			d.Runtime(),
+			key,
+			strings.Join(order, ""))
+	}
+
+	for _, field := range t.Fields {
+		// encoded values are stored as json and cannot
+		// be compared.
+		switch field.Node.Kind {
+		case parse.Map, parse.Slice, parse.Struct, parse.Ptr:
+			continue
+		}
+		column := inflect.Camelize(field.Name[2:])
+		fmt.Fprintf(w, sQueryColumn,
+			column, field.Name,
+			tree.Type, column, getParamType(field.Node), tree.Type,
+			field.Name,
+			column, field.Name,
+			tree.Type, column, tree.Type,
+			field.Name)
+	}
+}
+
+// writeQueryAllFunc writes the method selecting the rows
+// of the table's query builder.
+func writeQueryAllFunc(srcPkgNameInShort string, w io.Writer, tree *parse.Node) {
+	fmt.Fprintf(w, sQueryAll, tree.Type, srcPkgNameInShort+"."+tree.Type, getWrapCode(tree, tree.Type+"QueryBuilder.All"), tree.Type)
+}
+
+// writeFindSortedFunc writes the variants of the find
+// all and find by index functions sorted by a column.
+func writeFindSortedFunc(srcPkgNameInShort string, w io.Writer, tree *parse.Node, t *schema.Table) {
+	name := inflect.CamelizeDownFirst(tree.Type)
+	for _, ranged := range []bool{false, true} {
+		var rangeParams, rangeArgs, rangeLabel, suffix string
+		if ranged {
+			rangeParams, rangeArgs, rangeLabel, suffix = ", limit int64, offset int64", "limit, offset", "range", "InRange"
+		}
+
+		fmt.Fprintf(w, sFindSorted,
+			"All"+tree.Type+"s",
+			suffix,
+			"",
+			tree.Type,
+			rangeParams,
+			srcPkgNameInShort+"."+tree.Type,
+			getWrapCode(tree, "FindAll"+tree.Type+"s"+suffix+"Sorted"),
+			name,
+			rangeArgs,
+			tree.Type,
+			getLabelName(nonEmpty("select", inflect.Singularize(t.Name), "sorted", rangeLabel, "stmt")...))
+
+		for _, ix := range t.Index {
+			if ix.Unique {
+				continue
+			}
+			args := joinObjectFieldInDetails(ix.Fields, ", ", false)
+			if ranged {
+				args += ", " + rangeArgs
+			}
+			fmt.Fprintf(w, sFindSorted,
+				tree.Type+"s",
+				getLabelName("by", joinField(ix.Fields, "And"))+suffix,
+				joinObjectFieldInDetails(ix.Fields, ", ", true)+", ",
+				tree.Type,
+				rangeParams,
+				srcPkgNameInShort+"."+tree.Type,
+				getWrapCode(tree, "Find"+tree.Type+"s"+getLabelName("by", joinField(ix.Fields, "And"))+suffix+"Sorted"),
+				name,
+				args,
+				tree.Type,
+				getLabelName(nonEmpty("select", inflect.Singularize(t.Name), "sorted", rangeLabel, "by", joinField(ix.Fields, "And"), "stmt")...))
+		}
+	}
+}
+
+// nonEmpty returns the labels that are not empty.
+func nonEmpty(labels ...string) []string {
+	var parts []string
+	for _, label := range labels {
+		if label != "" {
+			parts = append(parts, label)
+		}
+	}
+	return parts
+}
+
+// writeFindAfterFunc writes the functions paging through
+// all rows, and the rows of each non-unique index, by the
+// primary key.
+func writeFindAfterFunc(srcPkgNameInShort string, w io.Writer, tree *parse.Node, t *schema.Table) {
+	if len(t.Primary) == 0 {
+		return
+	}
+
+	name := inflect.CamelizeDownFirst(tree.Type)
+	fmt.Fprintf(w, sFindAfter,
+		tree.Type,
+		"",
+		"",
+		srcPkgNameInShort+"."+tree.Type,
+		getWrapCode(tree, "Find"+tree.Type+"sAfter"),
+		"",
+		getLabelName("select", inflect.Singularize(t.Name), "page", "stmt"),
+		name,
+		getLabelName("select", inflect.Singularize(t.Name), "after", "stmt"),
+		tree.Type,
+		name)
+
+	for _, ix := range t.Index {
+		if ix.Unique {
+			continue
+		}
+		fmt.Fprintf(w, sFindAfter,
+			tree.Type,
+			getLabelName("by", joinField(ix.Fields, "And")),
+			joinObjectFieldInDetails(ix.Fields, ", ", true)+", ",
+			srcPkgNameInShort+"."+tree.Type,
+			getWrapCode(tree, "Find"+tree.Type+"s"+getLabelName("by", joinField(ix.Fields, "And"))+"After"),
+			joinObjectFieldInDetails(ix.Fields, ", ", false),
+			getLabelName("select", inflect.Singularize(t.Name), "page", "by", joinField(ix.Fields, "And"), "stmt"),
+			name,
+			getLabelName("select", inflect.Singularize(t.Name), "after", "by", joinField(ix.Fields, "And"), "stmt"),
+			tree.Type,
+			name)
+	}
+}
+
 func writeFindAllFunc(srcPkgNameInShort string, w io.Writer,  tree *parse.Node, t *schema.Table){
-	fmt.Fprintf(w, sFindAll, tree.Type, srcPkgNameInShort+"."+tree.Type, tree.Type, getLabelName("select", inflect.Singularize(t.Name), "stmt"))
+	fmt.Fprintf(w, sFindAll, tree.Type, srcPkgNameInShort+"."+tree.Type, getWrapCode(tree, "FindAll"+tree.Type+"s"), tree.Type, getLabelName("select", inflect.Singularize(t.Name), "stmt"))
 }
 
 func writeFindAllInRangeFunc(srcPkgNameInShort string, w io.Writer,  tree *parse.Node, t *schema.Table){
-	fmt.Fprintf(w, sFindAllInRange, tree.Type, srcPkgNameInShort+"."+tree.Type, tree.Type, getLabelName("select", inflect.Singularize(t.Name), "range", "stmt"))
+	fmt.Fprintf(w, sFindAllInRange, tree.Type, srcPkgNameInShort+"."+tree.Type, getWrapCode(tree, "FindAll"+tree.Type+"sInRange"), tree.Type, getLabelName("select", inflect.Singularize(t.Name), "range", "stmt"))
 }
 
 func writeCountAllFunc(w io.Writer,  tree *parse.Node, t *schema.Table){
-	fmt.Fprintf(w, sCount, tree.Type,  getLabelName("select", inflect.Singularize(t.Name), "count", "stmt"))
+	fmt.Fprintf(w, sCount, tree.Type,  getWrapCode(tree, "Count"+tree.Type),  getLabelName("select", inflect.Singularize(t.Name), "count", "stmt"))
 }
 
 func writeCountByIndexFunc(w io.Writer,  tree *parse.Node, t *schema.Table){
@@ -456,12 +1350,33 @@ func writeCountByIndexFunc(w io.Writer,  tree *parse.Node, t *schema.Table){
 				tree.Type,
 				getLabelName("by", joinField(ix.Fields, "And")),
 				joinObjectFieldInDetails(ix.Fields, ",", true),
+				getWrapCode(tree, "Count"+tree.Type+getLabelName("by", joinField(ix.Fields, "And"))),
 				joinObjectFieldInDetails(ix.Fields, ",", false),
 				getLabelName("select", inflect.Singularize(t.Name), "count", "by", joinField(ix.Fields, "And"), "stmt"))
 		}
 	}
 }
 
+// getAuto returns the auto-increment field of the table,
+// or nil if the table has none.
+func getAuto(t *schema.Table) *schema.Field {
+	for _, field := range t.Fields {
+		if field.Auto {
+			return field
+		}
+	}
+	return nil
+}
+
+// qualify returns the type name of the node as it is
+// referenced from the generated package.
+func qualify(srcPkgNameInShort string, tree, node *parse.Node) string {
+	if node.Import != tree.Import {
+		return node.Type
+	}
+	return srcPkgNameInShort + "." + node.Type
+}
+
 // join is a helper function that joins nodes
 // together by name using the seperator.
 func join(nodes []*parse.Node, sep string) string {
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/linchunquan/sqlgen/parse"
+	"github.com/linchunquan/sqlgen/schema"
+)
+
+func TestBeforeCode(t *testing.T) {
+	page := &parse.Node{Type: "Page", Hooks: []string{parse.BeforeInsert}}
+	updated := &parse.Node{Name: "Updated", Parent: page}
+	table := &schema.Table{
+		Fields: []*schema.Field{{Name: "f_updated", Node: updated, AutoUpdateTime: true}},
+	}
+
+	want := "if err := v.BeforeInsert(); err != nil {\nreturn err\n}\npageStampInsert(v)\n"
+	if got := getBeforeCode(page, table, true); got != want {
+		t.Errorf("Wanted insert code %q, got %q", want, got)
+	}
+	want = "pageStampUpdate(v)\n"
+	if got := getBeforeCode(page, table, false); got != want {
+		t.Errorf("Wanted update code %q, got %q", want, got)
+	}
+	if got := getAfterInsertCode(page); got != "" {
+		t.Errorf("Wanted no AfterInsert call, got %q", got)
+	}
+}
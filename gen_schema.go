@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"bitbucket.org/pkg/inflect"
+	"github.com/linchunquan/sqlgen/parse"
 	"github.com/linchunquan/sqlgen/schema"
 	"path/filepath"
 	"os"
@@ -51,6 +52,11 @@ func writeSchema(w io.Writer, d schema.Dialect, t *schema.Table, outputSqlFilePa
 			d.Insert(t),
 			"insert", inflect.Singularize(t.Name), "stmt",
 		)
+
+		writeConst(nil, w,
+			d.InsertBatch(t),
+			"insert", inflect.Singularize(t.Name), "batch", "stmt",
+		)
 	}
 
 
@@ -69,15 +75,39 @@ func writeSchema(w io.Writer, d schema.Dialect, t *schema.Table, outputSqlFilePa
 		"select", inflect.Singularize(t.Name), "count", "stmt",
 	)
 
+	writeConst(nil, w,
+		d.SelectSorted(t, nil, false),
+		"select", inflect.Singularize(t.Name), "sorted", "stmt",
+	)
+
+	writeConst(nil, w,
+		d.SelectQuery(t),
+		"select", inflect.Singularize(t.Name), "query", "stmt",
+	)
+
+	writeConst(nil, w,
+		d.SelectSorted(t, nil, true),
+		"select", inflect.Singularize(t.Name), "sorted", "range", "stmt",
+	)
+
 	if len(t.Primary) != 0 {
+		writeConst(nil, w,
+			d.SelectAfter(t, nil, false), "select", inflect.Singularize(t.Name), "page", "stmt",
+		)
+		writeConst(nil, w,
+			d.SelectAfter(t, nil, true), "select", inflect.Singularize(t.Name), "after", "stmt",
+		)
+
 		writeConst(nil, w,
 			d.Select(t, t.Primary), "select", inflect.Singularize(t.Name), "by", joinField(t.Primary, "And"), "stmt",
 		)
 
-		if !view{
+		if !view && len(updateFields(t)) != 0 {
 			writeConst(nil, w,
-				d.Update(t, t.Primary), "update", inflect.Singularize(t.Name), "by", joinField(t.Primary, "And"), "stmt",
+				d.UpdateColumns(t, updateFields(t), t.Primary), "update", inflect.Singularize(t.Name), "by", joinField(t.Primary, "And"), "stmt",
 			)
+		}
+		if !view{
 			writeConst(nil, w,
 				d.Delete(t, t.Primary), "delete", inflect.Singularize(t.Name), "by", joinField(t.Primary, "And"), "stmt",
 			)
@@ -108,12 +138,32 @@ func writeSchema(w io.Writer, d schema.Dialect, t *schema.Table, outputSqlFilePa
 				d.SelectRange(t, ix.Fields),
 				"select", inflect.Singularize(t.Name), "range", "by", joinField(ix.Fields, "And"), "stmt",
 			)
+			writeConst(nil, w,
+				d.SelectSorted(t, ix.Fields, false),
+				"select", inflect.Singularize(t.Name), "sorted", "by", joinField(ix.Fields, "And"), "stmt",
+			)
+			writeConst(nil, w,
+				d.SelectSorted(t, ix.Fields, true),
+				"select", inflect.Singularize(t.Name), "sorted", "range", "by", joinField(ix.Fields, "And"), "stmt",
+			)
+			if len(t.Primary) != 0 {
+				writeConst(nil, w,
+					d.SelectAfter(t, ix.Fields, false),
+					"select", inflect.Singularize(t.Name), "page", "by", joinField(ix.Fields, "And"), "stmt",
+				)
+				writeConst(nil, w,
+					d.SelectAfter(t, ix.Fields, true),
+					"select", inflect.Singularize(t.Name), "after", "by", joinField(ix.Fields, "And"), "stmt",
+				)
+			}
 		} else {
-			if !view{
+			if !view && len(updateFields(t)) != 0 {
 				writeConst(nil, w,
-					d.Update(t, ix.Fields),
+					d.UpdateColumns(t, updateFields(t), ix.Fields),
 					"update", inflect.Singularize(t.Name), "by", joinField(ix.Fields, "And"), "stmt",
 				)
+			}
+			if !view{
 				/*writeConst(nil, w,
 					d.Delete(t, ix.Fields),
 					"delete", inflect.Singularize(t.Name), "by", joinField(ix.Fields, "And"), "stmt",
@@ -127,6 +177,23 @@ func writeSchema(w io.Writer, d schema.Dialect, t *schema.Table, outputSqlFilePa
 		)
 	}
 
+	if !view {
+		for _, key := range schema.UpsertKeys(t) {
+			writeConst(nil, w,
+				d.Upsert(t, key),
+				"upsert", inflect.Singularize(t.Name), "by", joinField(key, "And"), "stmt",
+			)
+			// the version the upsert leaves the row with is
+			// selected after it without RETURNING.
+			if schema.VersionField(t) != nil && !d.Returning() {
+				writeConst(nil, w,
+					d.SelectVersion(t, key),
+					"select", inflect.Singularize(t.Name), "version", "by", joinField(key, "And"), "stmt",
+				)
+			}
+		}
+	}
+
 	for _, fk := range t.Foreigns{
 		if !view {
 			writeConst(sqlFileContent, w,
@@ -148,11 +215,58 @@ func writeSchema(w io.Writer, d schema.Dialect, t *schema.Table, outputSqlFilePa
 		}
 	}
 
+	if schema.SoftDeleteField(t) != nil {
+		writeWithDeletedSchema(w, d, t, view)
+	}
+
 	if outputSql{
 		sqlFile.Write(sqlFileContent.Bytes())
 	}
 }
 
+// writeWithDeletedSchema writes the statements of a table
+// with a soft delete field that select the rows marked as
+// deleted along with the others, and that delete physically.
+func writeWithDeletedSchema(w io.Writer, d schema.Dialect, t *schema.Table, view bool) {
+	all := schema.WithDeleted(t)
+	writeConst(nil, w,
+		d.Select(all, nil),
+		"select", inflect.Singularize(t.Name), "with", "deleted", "stmt",
+	)
+	writeConst(nil, w,
+		d.SelectCount(all, nil),
+		"select", inflect.Singularize(t.Name), "count", "with", "deleted", "stmt",
+	)
+
+	if len(t.Primary) != 0 {
+		writeConst(nil, w,
+			d.Select(all, t.Primary),
+			"select", inflect.Singularize(t.Name), "by", joinField(t.Primary, "And"), "with", "deleted", "stmt",
+		)
+		if !view {
+			writeConst(nil, w,
+				d.Delete(all, t.Primary),
+				"hard", "delete", inflect.Singularize(t.Name), "by", joinField(t.Primary, "And"), "stmt",
+			)
+		}
+	}
+
+	for _, ix := range t.Index {
+		if ix.Unique {
+			writeConst(nil, w,
+				d.Select(all, ix.Fields),
+				"select", inflect.Singularize(t.Name), "by", joinField(ix.Fields, "And"), "with", "deleted", "stmt",
+			)
+		}
+		if !view {
+			writeConst(nil, w,
+				d.Delete(all, ix.Fields),
+				"hard", "delete", inflect.Singularize(t.Name), "by", joinField(ix.Fields, "And"), "stmt",
+			)
+		}
+	}
+}
+
 // WritePackage writes the Go package header to
 // writer w with the given package name.
 func writePackage(w io.Writer, name string) {
@@ -234,15 +348,39 @@ func joinObjectFieldInDetails(fields[]*schema.Field, sep string, withType bool)s
 		if i==0{
 			buf.WriteString(inflect.CamelizeDownFirst(field.Node.Name))
 			if withType{
-				buf.WriteString(" "+field.Node.Type)
+				buf.WriteString(" "+getParamType(field.Node))
 			}
 		}else{
 			buf.WriteString(sep)
 			buf.WriteString(inflect.CamelizeDownFirst(field.Node.Name))
 			if withType{
-				buf.WriteString(" "+field.Node.Type)
+				buf.WriteString(" "+getParamType(field.Node))
 			}
 		}
 	}
 	return buf.String()
-}
\ No newline at end of file
+}
+
+// updateFields returns the fields set by the generated
+// updates, which leave out the primary key and auto columns
+// unless -updateKeys is true.
+func updateFields(t *schema.Table) []*schema.Field {
+	if *updateKeys {
+		return t.Fields
+	}
+	return schema.SetFields(t)
+}
+
+// getParamType returns the Go type of the node as it is
+// referenced from the generated package.
+func getParamType(node *parse.Node) string {
+	switch {
+	case node.Named != "" && !strings.Contains(node.Named, "."):
+		return node.Pkg + "." + node.Named
+	case node.Named != "":
+		return node.Named
+	case node.Kind == parse.Valuer && !strings.Contains(node.Type, "."):
+		return node.Pkg + "." + node.Type
+	}
+	return node.Type
+}
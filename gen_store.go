@@ -0,0 +1,772 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"bitbucket.org/pkg/inflect"
+	"github.com/acsellers/inflections"
+	"github.com/linchunquan/sqlgen/parse"
+	"github.com/linchunquan/sqlgen/schema"
+)
+
+// storeMethod describes a generated function exposed as a
+// method of the table's store interface.
+type storeMethod struct {
+	name    string // name of the generated function
+	params  string // typed parameters, following db
+	args    string // arguments passed on, following db
+	results string
+	fake    string // body of the in-memory method
+}
+
+// storeKey is a primary key or unique index, which the
+// in-memory store keeps unique.
+type storeKey struct {
+	name   string
+	fields []*schema.Field
+}
+
+// writeStore writes the store interface of a table, with
+// an implementation calling the generated functions and an
+// in-memory implementation for tests.
+func writeStore(srcPkgNameInShort string, w io.Writer, tree *parse.Node, t *schema.Table, d schema.Dialect, form apiForm) {
+	typ := srcPkgNameInShort + "." + tree.Type
+	methods := getStoreMethods(srcPkgNameInShort, tree, t)
+
+	// the store follows the form of the generated api,
+	// taking a context when the context functions are
+	// generated.
+	var iface, impl, fake bytes.Buffer
+	for _, m := range methods {
+		params := join2(form.CtxParam, m.params)
+		fmt.Fprintf(&iface, "%s(%s) %s\n", m.name, params, m.results)
+		fmt.Fprintf(&impl, "\nfunc (s *sql%sStore) %s(%s) %s {\nreturn %s%s(%ss.db%s)\n}\n",
+			tree.Type, m.name, params, m.results, m.name, form.Ctx, form.CtxArg, prefixComma(m.args))
+		// the in-memory errors are wrapped as by the
+		// function the sql method calls.
+		fmt.Fprintf(&fake, "\nfunc (f *fake%sStore) %s(%s) %s {\n%s%s\n}\n",
+			tree.Type, m.name, params, namedResults(m.results), getWrapCode(tree, m.name), m.fake)
+	}
+
+	fmt.Fprintf(w, sStore,
+		tree.Type, tree.Type,
+		tree.Type, iface.String(),
+		tree.Type, form.DB,
+		tree.Type, tree.Type,
+		tree.Type, form.DB, tree.Type, tree.Type,
+		impl.String())
+
+	// the helper functions of the in-memory store are
+	// written with the type substituted by name, since
+	// it is repeated throughout.
+	helpers := strings.NewReplacer(
+		"{{.Type}}", tree.Type,
+		"{{.Qualified}}", typ,
+		"{{.Conflict}}", getConflictCode(t),
+		"{{.NextId}}", getNextIdCode(t),
+		"{{.After}}", getFakeAfterCode(typ, tree, t),
+		"{{.Name}}", inflect.CamelizeDownFirst(tree.Type),
+		"{{.SortKey}}", getFakeSortKeyCode(tree, t),
+		"{{.ColumnValues}}", getColumnValueCode(tree, t),
+		"{{.Order}}", getFakeOrderCode(tree, t, d),
+		"{{.Clone}}", getCloneCode(tree, "c"),
+		"{{.AfterScan}}", getFakeAfterScanCode(tree),
+		"{{.Dialect}}", d.Runtime(),
+	).Replace(sFakeStore)
+	io.WriteString(w, helpers)
+	io.WriteString(newAPIWriter(w, form), fake.String())
+}
+
+// getStoreMethods returns the store methods of a table,
+// one for each generated function.
+func getStoreMethods(srcPkgNameInShort string, tree *parse.Node, t *schema.Table) []storeMethod {
+	var methods []storeMethod
+	typ := srcPkgNameInShort + "." + tree.Type
+	plural := tree.Type + "s"
+
+	// the rows marked as deleted are hidden from every
+	// method but the WithDeleted variants, as in the selects.
+	live := getLiveCode(t)
+	all := "func(*" + typ + ") bool { return true }"
+	if live != "" {
+		all = fmt.Sprintf("func(v *%s) bool { return %s }", typ, live)
+	}
+	match := func(fields []*schema.Field) string {
+		cond := getMatchCode(fields)
+		if live != "" {
+			cond = live + " && " + cond
+		}
+		return fmt.Sprintf("func(v *%s) bool { return %s }", typ, cond)
+	}
+	matchDeleted := func(fields []*schema.Field) string {
+		return fmt.Sprintf("func(v *%s) bool { return %s }", typ, getMatchCode(fields))
+	}
+	by := func(fields []*schema.Field) string {
+		return getLabelName("by", joinField(fields, "And"))
+	}
+	params := func(fields []*schema.Field) string {
+		return joinObjectFieldInDetails(fields, ", ", true)
+	}
+	args := func(fields []*schema.Field) string {
+		return joinObjectFieldInDetails(fields, ", ", false)
+	}
+
+	var keys []storeKey
+	if len(t.Primary) != 0 {
+		keys = append(keys, storeKey{"primary", t.Primary})
+	}
+	for _, ix := range t.Index {
+		if ix.Unique {
+			keys = append(keys, storeKey{ix.Name, ix.Fields})
+		}
+	}
+
+	if !*view {
+		// the hooks are called and the automatic timestamps
+		// set as by the generated functions.
+		beforeInsert := getBeforeCode(tree, t, true)
+		beforeUpdate := getBeforeCode(tree, t, false)
+		insert := "return f.insert(v, false)"
+		if after := getAfterInsertCode(tree); after != "" {
+			insert = "if err := f.insert(v, false); err != nil {\nreturn err\n}\n" + after + "return nil"
+		}
+		methods = append(methods, storeMethod{
+			name:    "Insert" + tree.Type,
+			params:  "v *" + typ,
+			args:    "v",
+			results: "error",
+			fake:    beforeInsert + insert,
+		})
+		if len(t.Fields) != len(getAutoFields(t)) {
+			methods = append(methods, storeMethod{
+				name:    "Insert" + inflections.Pluralize(tree.Type),
+				params:  "vv []*" + typ,
+				args:    "vv",
+				results: "error",
+				fake:    "for _, v := range vv {\n" + beforeInsert + "if err := f.insert(v, false); err != nil {\nreturn err\n}\n" + getAfterInsertCode(tree) + "}\nreturn nil",
+			})
+		}
+		// a versioned row is only updated if it still has
+		// the version of v, which is then incremented.
+		version := schema.VersionField(t)
+		var check, bump string
+		if version != nil {
+			path := join(version.Node.Path()[1:], ".")
+			check = fmt.Sprintf("if i == -1 || !db.Equal(f.rows[i].%s, v.%s) {\nreturn &db.ErrStaleObject{Table: %q}\n}\n", path, path, t.Name)
+			bump = fmt.Sprintf("c.%s = v.%s + 1\nif err := f.update(i, &c); err != nil {\nreturn err\n}\nv.%s++\nreturn nil", path, path, path)
+		}
+
+		for _, key := range keys {
+			if len(updateFields(t)) == 0 {
+				break
+			}
+			// without -updateKeys the row keeps its primary
+			// and auto fields, which the update does not set.
+			var keep bytes.Buffer
+			if !*updateKeys {
+				keep.WriteString(getKeepCode(t, nil))
+			}
+			fake := fmt.Sprintf("%sc := *v\nif i := f.index(%s); i != -1 {\n%sreturn f.update(i, &c)\n}\nreturn nil",
+				beforeUpdate, getKeyMatchCode(typ, key.fields), keep.String())
+			if version != nil {
+				fake = fmt.Sprintf("%sc := *v\ni := f.index(%s)\n%s%s%s",
+					beforeUpdate, getKeyMatchCode(typ, key.fields), check, keep.String(), bump)
+			}
+			methods = append(methods, storeMethod{
+				name:    "Update" + tree.Type + by(key.fields),
+				params:  "v *" + typ,
+				args:    "v",
+				results: "error",
+				fake:    fake,
+			})
+		}
+		if len(t.Primary) != 0 {
+			find := "if i == -1 {\nreturn nil\n}\n"
+			update := "return f.update(i, &c)"
+			if version != nil {
+				find, update = check, bump
+			}
+			var touch bytes.Buffer
+			_, updateTimes := schema.AutoTimeFields(t)
+			for _, field := range updateTimes {
+				path := join(field.Node.Path()[1:], ".")
+				fmt.Fprintf(&touch, "c.%s = v.%s\n", path, path)
+			}
+			methods = append(methods, storeMethod{
+				name:    "Update" + tree.Type + "Fields",
+				params:  "v *" + typ + ", cols ..." + tree.Type + "Column",
+				args:    "v, cols...",
+				results: "error",
+				fake: fmt.Sprintf("if len(cols) == 0 {\nreturn nil\n}\n%s%si := f.index(%s)\n%sc := *f.rows[i]\nfor _, col := range cols {\nswitch col {\n%sdefault:\nreturn fmt.Errorf(\"invalid %s column %%d\", col)\n}\n}\n%s%s",
+					beforeUpdate, getKeyRejectCode(tree, t), getKeyMatchCode(typ, t.Primary), find, getColumnAssignCode(srcPkgNameInShort, tree, t), t.Name, touch.String(), update),
+			})
+		}
+		for _, fields := range schema.UpsertKeys(t) {
+			// the conflicting row keeps its primary and
+			// auto fields and creation times, which the
+			// upsert does not update. A row marked as
+			// deleted is revived, as by the statement.
+			keep := getKeepCode(t, fields)
+			createTimes, _ := schema.AutoTimeFields(t)
+			for _, field := range createTimes {
+				path := join(field.Node.Path()[1:], ".")
+				keep += fmt.Sprintf("c.%s = f.rows[i].%s\n", path, path)
+			}
+			// the version of the conflicting row is
+			// incremented and set in v, as by the statement.
+			var sync string
+			if version != nil {
+				path := join(version.Node.Path()[1:], ".")
+				keep += fmt.Sprintf("c.%s = f.rows[i].%s + 1\n", path, path)
+				sync = fmt.Sprintf("v.%s = c.%s\n", path, path)
+			}
+			upsert := fmt.Sprintf("%sc := *v\nif i := f.index(%s); i != -1 {\n%sreturn f.update(i, &c)\n}\nreturn f.insert(&c, %t)",
+				beforeInsert, getKeyMatchCode(typ, fields), keep, hasAuto(fields))
+			if after := getAfterInsertCode(tree); after != "" || sync != "" {
+				upsert = fmt.Sprintf("%sc := *v\nif i := f.index(%s); i != -1 {\n%sif err := f.update(i, &c); err != nil {\nreturn err\n}\n%s} else if err := f.insert(&c, %t); err != nil {\nreturn err\n}\n%sreturn nil",
+					beforeInsert, getKeyMatchCode(typ, fields), keep, sync, hasAuto(fields), after)
+			}
+			methods = append(methods, storeMethod{
+				name:    "Upsert" + tree.Type + by(fields),
+				params:  "v *" + typ,
+				args:    "v",
+				results: "error",
+				fake:    upsert,
+			})
+		}
+		var deletes [][]*schema.Field
+		if len(t.Primary) != 0 {
+			deletes = append(deletes, t.Primary)
+		}
+		for _, ix := range t.Index {
+			deletes = append(deletes, ix.Fields)
+		}
+		for _, fields := range deletes {
+			methods = append(methods, storeMethod{
+				name:    "Delete" + tree.Type + by(fields),
+				params:  params(fields),
+				args:    args(fields),
+				results: "error",
+				fake:    getFakeDeleteCode(t, match(fields)),
+			})
+		}
+	}
+
+	for _, key := range keys {
+		methods = append(methods, storeMethod{
+			name:    "Get" + tree.Type + by(key.fields),
+			params:  params(key.fields),
+			args:    args(key.fields),
+			results: fmt.Sprintf("(*%s, error)", typ),
+			fake:    fmt.Sprintf("return f.get(%s)", match(key.fields)),
+		})
+	}
+
+	methods = append(methods, storeMethod{
+		name:    "FindAll" + plural,
+		results: fmt.Sprintf("([]*%s, error)", typ),
+		fake:    "return f.find(" + all + ")",
+	}, storeMethod{
+		name:    "FindAll" + plural + "InRange",
+		params:  "limit int64, offset int64",
+		args:    "limit, offset",
+		results: fmt.Sprintf("([]*%s, error)", typ),
+		fake:    "vv, err := f.find(" + all + ")\nreturn page" + tree.Type + "s(vv, limit, offset), err",
+	})
+
+	for _, ix := range t.Index {
+		if ix.Unique {
+			continue
+		}
+		methods = append(methods, storeMethod{
+			name:    "Find" + plural + by(ix.Fields),
+			params:  params(ix.Fields),
+			args:    args(ix.Fields),
+			results: fmt.Sprintf("([]*%s, error)", typ),
+			fake:    fmt.Sprintf("return f.find(%s)", match(ix.Fields)),
+		}, storeMethod{
+			name:    "Find" + plural + by(ix.Fields) + "InRange",
+			params:  params(ix.Fields) + ", limit int64, offset int64",
+			args:    args(ix.Fields) + ", limit, offset",
+			results: fmt.Sprintf("([]*%s, error)", typ),
+			fake:    fmt.Sprintf("vv, err := f.find(%s)\nreturn page%ss(vv, limit, offset), err", match(ix.Fields), tree.Type),
+		})
+	}
+
+	for _, ranged := range []bool{false, true} {
+		var rangeParams, rangeArgs, suffix, page string
+		if ranged {
+			rangeParams, rangeArgs, suffix = ", limit int64, offset int64", ", limit, offset", "InRange"
+			page = fmt.Sprintf("vv, err := f.sorted(%%s, by, dir)\nreturn page%ss(vv, limit, offset), err", tree.Type)
+		} else {
+			page = "return f.sorted(%s, by, dir)"
+		}
+		methods = append(methods, storeMethod{
+			name:    "FindAll" + plural + suffix + "Sorted",
+			params:  "by " + tree.Type + "Column, dir db.Direction" + rangeParams,
+			args:    "by, dir" + rangeArgs,
+			results: fmt.Sprintf("([]*%s, error)", typ),
+			fake:    fmt.Sprintf(page, all),
+		})
+		for _, ix := range t.Index {
+			if ix.Unique {
+				continue
+			}
+			methods = append(methods, storeMethod{
+				name:    "Find" + plural + by(ix.Fields) + suffix + "Sorted",
+				params:  params(ix.Fields) + ", by " + tree.Type + "Column, dir db.Direction" + rangeParams,
+				args:    args(ix.Fields) + ", by, dir" + rangeArgs,
+				results: fmt.Sprintf("([]*%s, error)", typ),
+				fake:    fmt.Sprintf(page, match(ix.Fields)),
+			})
+		}
+	}
+
+	if len(t.Primary) != 0 {
+		methods = append(methods, storeMethod{
+			name:    "Find" + plural + "After",
+			params:  "cursor string, limit int64",
+			args:    "cursor, limit",
+			results: fmt.Sprintf("([]*%s, string, error)", typ),
+			fake:    "return f.after(" + all + ", cursor, limit)",
+		})
+		for _, ix := range t.Index {
+			if ix.Unique {
+				continue
+			}
+			methods = append(methods, storeMethod{
+				name:    "Find" + plural + by(ix.Fields) + "After",
+				params:  params(ix.Fields) + ", cursor string, limit int64",
+				args:    args(ix.Fields) + ", cursor, limit",
+				results: fmt.Sprintf("([]*%s, string, error)", typ),
+				fake:    fmt.Sprintf("return f.after(%s, cursor, limit)", match(ix.Fields)),
+			})
+		}
+	}
+
+	for _, fk := range t.Foreigns {
+		of := inflect.Camelize(fk.ToTable[:len(fk.ToTable)-1])
+		if !fk.Many {
+			methods = append(methods, storeMethod{
+				name:    "Get" + tree.Type + "Of" + of + by(fk.FromFields),
+				params:  params(fk.FromFields),
+				args:    args(fk.FromFields),
+				results: fmt.Sprintf("(*%s, error)", typ),
+				fake:    fmt.Sprintf("return f.get(%s)", match(fk.FromFields)),
+			})
+			continue
+		}
+		methods = append(methods, storeMethod{
+			name:    "Find" + plural + "Of" + of + by(fk.FromFields),
+			params:  params(fk.FromFields),
+			args:    args(fk.FromFields),
+			results: fmt.Sprintf("([]*%s, error)", typ),
+			fake:    fmt.Sprintf("return f.find(%s)", match(fk.FromFields)),
+		}, storeMethod{
+			name:    "Find" + plural + "Of" + of + by(fk.FromFields) + "InRange",
+			params:  params(fk.FromFields) + ", limit int64, offset int64",
+			args:    args(fk.FromFields) + ", limit, offset",
+			results: fmt.Sprintf("([]*%s, error)", typ),
+			fake:    fmt.Sprintf("vv, err := f.find(%s)\nreturn page%ss(vv, limit, offset), err", match(fk.FromFields), tree.Type),
+		})
+	}
+
+	methods = append(methods, storeMethod{
+		name:    "Count" + tree.Type,
+		results: "(int, error)",
+		fake:    "return f.count(" + all + "), nil",
+	})
+	for _, ix := range t.Index {
+		methods = append(methods, storeMethod{
+			name:    "Count" + tree.Type + by(ix.Fields),
+			params:  params(ix.Fields),
+			args:    args(ix.Fields),
+			results: "(int, error)",
+			fake:    fmt.Sprintf("return f.count(%s), nil", match(ix.Fields)),
+		})
+	}
+
+	if live == "" {
+		return methods
+	}
+	methods = append(methods, storeMethod{
+		name:    "FindAll" + plural + "WithDeleted",
+		results: fmt.Sprintf("([]*%s, error)", typ),
+		fake:    "return f.find(func(*" + typ + ") bool { return true })",
+	}, storeMethod{
+		name:    "Count" + tree.Type + "WithDeleted",
+		results: "(int, error)",
+		fake:    "return len(f.rows), nil",
+	})
+	for _, key := range keys {
+		methods = append(methods, storeMethod{
+			name:    "Get" + tree.Type + by(key.fields) + "WithDeleted",
+			params:  params(key.fields),
+			args:    args(key.fields),
+			results: fmt.Sprintf("(*%s, error)", typ),
+			fake:    fmt.Sprintf("return f.get(%s)", matchDeleted(key.fields)),
+		})
+	}
+	if !*view {
+		var deletes [][]*schema.Field
+		if len(t.Primary) != 0 {
+			deletes = append(deletes, t.Primary)
+		}
+		for _, ix := range t.Index {
+			deletes = append(deletes, ix.Fields)
+		}
+		for _, fields := range deletes {
+			methods = append(methods, storeMethod{
+				name:    "HardDelete" + tree.Type + by(fields),
+				params:  params(fields),
+				args:    args(fields),
+				results: "error",
+				fake:    fmt.Sprintf("f.delete(%s)\nreturn nil", matchDeleted(fields)),
+			})
+		}
+	}
+	return methods
+}
+
+// getLiveCode returns the condition on v excluding the rows
+// marked as deleted, or an empty string if the table has no
+// soft delete field.
+func getLiveCode(t *schema.Table) string {
+	field := schema.SoftDeleteField(t)
+	if field == nil {
+		return ""
+	}
+	_, ref := getFieldRef("v", field)
+	if field.Type == schema.BOOLEAN {
+		return "!" + ref
+	}
+	return ref + " == nil"
+}
+
+// getFakeDeleteCode returns the body of the in-memory
+// delete of the rows matching the function, which marks the
+// rows as deleted if the table has a soft delete field.
+func getFakeDeleteCode(t *schema.Table, match string) string {
+	field := schema.SoftDeleteField(t)
+	if field == nil {
+		return fmt.Sprintf("f.delete(%s)\nreturn nil", match)
+	}
+
+	path := join(field.Node.Path()[1:], ".")
+	mark := fmt.Sprintf("c.%s = true\n", path)
+	if field.Type != schema.BOOLEAN {
+		mark = fmt.Sprintf("now := db.Now()\nc.%s = &now\n", path)
+	}
+	return fmt.Sprintf("for i, r := range f.rows {\nif (%s)(r) {\nc := *r\n%sf.rows[i] = &c\n}\n}\nreturn nil", match, mark)
+}
+
+// getMatchCode returns the expression matching the fields
+// of v against the function parameters of the same name.
+func getMatchCode(fields []*schema.Field) string {
+	var conds []string
+	for _, field := range fields {
+		guards, ref := getFieldRef("v", field)
+		conds = append(conds, guards...)
+		param := inflect.CamelizeDownFirst(field.Node.Name)
+		if field.Node.Pointer {
+			conds = append(conds, ref+" != nil", fmt.Sprintf("db.Equal(*%s, %s)", ref, param))
+		} else {
+			conds = append(conds, fmt.Sprintf("db.Equal(%s, %s)", ref, param))
+		}
+	}
+	return strings.Join(conds, " && ")
+}
+
+// getKeyMatchCode returns a function matching the rows
+// with the same key fields as v.
+func getKeyMatchCode(typ string, fields []*schema.Field) string {
+	var conds []string
+	for _, field := range fields {
+		guards, ref := getFieldRef("r", field)
+		conds = append(conds, guards...)
+		vguards, vref := getFieldRef("v", field)
+		conds = append(conds, vguards...)
+		conds = append(conds, fmt.Sprintf("db.Equal(%s, %s)", ref, vref))
+	}
+	return fmt.Sprintf("func(r *%s) bool { return %s }", typ, strings.Join(conds, " && "))
+}
+
+// getConflictCode returns the statements checking a row
+// against each primary key and unique index of the table.
+// As in SQL, a NULL column never conflicts.
+func getConflictCode(t *schema.Table) string {
+	var keys []storeKey
+	if len(t.Primary) != 0 {
+		keys = append(keys, storeKey{"primary", t.Primary})
+	}
+	for _, ix := range t.Index {
+		if ix.Unique {
+			keys = append(keys, storeKey{ix.Name, ix.Fields})
+		}
+	}
+
+	if len(keys) == 0 {
+		return "return nil\n"
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("for i, r := range f.rows {\nif i == skip {\ncontinue\n}\n")
+	for _, key := range keys {
+		var conds []string
+		for _, field := range key.fields {
+			guards, ref := getFieldRef("r", field)
+			conds = append(conds, guards...)
+			vguards, vref := getFieldRef("v", field)
+			conds = append(conds, vguards...)
+			if field.Node.Pointer {
+				conds = append(conds, ref+" != nil")
+			}
+			conds = append(conds, fmt.Sprintf("db.Equal(%s, %s)", ref, vref))
+		}
+		fmt.Fprintf(&buf, "if %s {\nreturn &db.ErrUniqueViolation{Index: %q}\n}\n", strings.Join(conds, " && "), key.name)
+	}
+	buf.WriteString("}\nreturn nil\n")
+	return buf.String()
+}
+
+// getNextIdCode returns the statements assigning the next
+// generated key to the auto-increment field of v.
+func getNextIdCode(t *schema.Table) string {
+	auto := getAuto(t)
+	if auto == nil || !strings.Contains(auto.Node.Type, "int") {
+		return ""
+	}
+	guards, ref := getFieldRef("v", auto)
+	code := fmt.Sprintf("f.next++\n%s = %s\n", ref, getIdConversion(auto.Node, "f.next"))
+	if len(guards) != 0 {
+		code = fmt.Sprintf("if %s {\n%s}\n", strings.Join(guards, " && "), code)
+	}
+	return fmt.Sprintf("if !keep {\n%s}\n", code)
+}
+
+// getFakeAfterCode returns the helpers of the in-memory
+// store paging by the primary key, if the table has one.
+func getFakeAfterCode(typ string, tree *parse.Node, t *schema.Table) string {
+	if len(t.Primary) == 0 {
+		return ""
+	}
+	var keys []string
+	for _, field := range t.Primary {
+		_, ref := getFieldRef("v", field)
+		if field.Node.Pointer {
+			ref = "*" + ref
+		}
+		keys = append(keys, ref)
+	}
+	return strings.NewReplacer(
+		"{{.Type}}", tree.Type,
+		"{{.Qualified}}", typ,
+		"{{.Name}}", inflect.CamelizeDownFirst(tree.Type),
+		"{{.Keys}}", strings.Join(keys, ", "),
+	).Replace(sFakeStoreAfter)
+}
+
+// getFakeSortKeyCode returns the statement sorting the
+// rows of the in-memory store by the primary key, if the
+// table has one.
+func getFakeSortKeyCode(tree *parse.Node, t *schema.Table) string {
+	if len(t.Primary) == 0 {
+		return ""
+	}
+	name := inflect.CamelizeDownFirst(tree.Type)
+	return fmt.Sprintf("\tdb.SortByKey(vv, func(i int) []interface{} {\n\t\treturn %sKey(vv[i])\n\t})\n", name)
+}
+
+// getFakeOrderCode returns the statement sorting the rows
+// of the in-memory store by the table's default order, if it
+// has one.
+func getFakeOrderCode(tree *parse.Node, t *schema.Table, d schema.Dialect) string {
+	fields := schema.OrderFields(t)
+	if len(fields) == 0 {
+		return ""
+	}
+	name := inflect.CamelizeDownFirst(tree.Type)
+	var values, desc []string
+	for _, field := range fields {
+		values = append(values, fmt.Sprintf("%sColumnValue(vv[i], %sColumn%s)", name, tree.Type, inflect.Camelize(field.Name[2:])))
+		desc = append(desc, fmt.Sprint(field.Order == "DESC"))
+	}
+	return fmt.Sprintf("\tdb.SortByColumns(vv, func(i int) []interface{} {\n\t\treturn []interface{}{%s}\n\t}, []bool{%s}, %s)\n",
+		strings.Join(values, ", "), strings.Join(desc, ", "), d.Runtime())
+}
+
+// getFakeAfterScanCode returns the statements calling the
+// AfterScan hook of the rows found, if the type has one.
+func getFakeAfterScanCode(tree *parse.Node) string {
+	if !tree.HasHook(parse.AfterScan) {
+		return ""
+	}
+	return "\tfor _, v := range vv {\n\t\tif err := v.AfterScan(); err != nil {\n\t\t\treturn nil, err\n\t\t}\n\t}\n"
+}
+
+// getCloneCode returns the statements replacing the
+// pointers, slices and maps of the fields under the node,
+// reached from recv, with copies. The fields of a pointer
+// struct are copied after the struct, if it is not nil.
+func getCloneCode(node *parse.Node, recv string) string {
+	var buf bytes.Buffer
+	for _, child := range node.Nodes {
+		ref := recv + "." + child.Name
+		switch {
+		case child.Kind == parse.Ptr:
+			fmt.Fprintf(&buf, "\tif %s != nil {\n\t\tdb.Clone(&%s)\n%s\t}\n", ref, ref, getCloneCode(child, ref))
+		case child.Kind == parse.Struct:
+			buf.WriteString(getCloneCode(child, ref))
+		case child.Pointer, child.Kind == parse.Slice, child.Kind == parse.Bytes, child.Kind == parse.Map:
+			fmt.Fprintf(&buf, "\tdb.Clone(&%s)\n", ref)
+		}
+	}
+	return buf.String()
+}
+
+// getColumnValueCode returns the cases of the switch
+// returning the value of a column of a row.
+func getColumnValueCode(tree *parse.Node, t *schema.Table) string {
+	var buf bytes.Buffer
+	for _, field := range t.Fields {
+		guards, ref := getFieldRef("v", field)
+		fmt.Fprintf(&buf, "\tcase %sColumn%s:\n", tree.Type, inflect.Camelize(field.Name[2:]))
+		if len(guards) != 0 {
+			fmt.Fprintf(&buf, "\t\tif %s {\n\t\t\treturn %s\n\t\t}\n", strings.Join(guards, " && "), ref)
+		} else {
+			fmt.Fprintf(&buf, "\t\treturn %s\n", ref)
+		}
+	}
+	return buf.String()
+}
+
+// getKeepCode returns the statements copying the primary
+// and auto fields, other than those of the key, from the
+// stored row i to the updated copy c.
+func getKeepCode(t *schema.Table, key []*schema.Field) string {
+	var buf bytes.Buffer
+	for _, field := range t.Fields {
+		if (field.Primary || field.Auto) && !containsField(key, field) {
+			path := join(field.Node.Path()[1:], ".")
+			fmt.Fprintf(&buf, "c.%s = f.rows[i].%s\n", path, path)
+		}
+	}
+	return buf.String()
+}
+
+// getKeyRejectCode returns the loop rejecting the key
+// columns among cols, which the update of the listed columns
+// may not set, as in the generated query.
+func getKeyRejectCode(tree *parse.Node, t *schema.Table) string {
+	cols := getKeyColumns(tree, t)
+	if len(cols) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("for _, col := range cols {\nswitch col {\ncase %s:\nreturn fmt.Errorf(\"cannot update %s key column %%s\", %sColumns[col])\n}\n}\n",
+		strings.Join(cols, ", "), t.Name, inflect.CamelizeDownFirst(tree.Type))
+}
+
+// getColumnAssignCode returns the cases of a switch on
+// the columns an update may set, copying the column from v to
+// the copy c of a stored row. Pointer structs on the way to
+// the column are copied rather than shared with the stored
+// row, and are left as they are when nil in v.
+func getColumnAssignCode(srcPkgNameInShort string, tree *parse.Node, t *schema.Table) string {
+	var buf bytes.Buffer
+	for _, field := range updateFields(t) {
+		fmt.Fprintf(&buf, "case %sColumn%s:\n", tree.Type, inflect.Camelize(field.Name[2:]))
+		guards, ref := getFieldRef("v", field)
+		if len(guards) != 0 {
+			fmt.Fprintf(&buf, "if %s {\n", strings.Join(guards, " && "))
+		}
+		path := field.Node.Path()[1:]
+		for i, node := range path[:len(path)-1] {
+			if node.Kind != parse.Ptr {
+				continue
+			}
+			parent := "c." + join(path[:i+1], ".")
+			fmt.Fprintf(&buf, "if %s == nil {\n%s = &%s{}\n} else {\np := *%s\n%s = &p\n}\n",
+				parent, parent, qualify(srcPkgNameInShort, tree, node), parent, parent)
+		}
+		fmt.Fprintf(&buf, "c.%s = %s\n", join(path, "."), ref)
+		if len(guards) != 0 {
+			buf.WriteString("}\n")
+		}
+	}
+	return buf.String()
+}
+
+// getFieldRef returns the reference to the field from the
+// named variable, along with the nil checks of any pointer
+// structs on the way to it.
+func getFieldRef(recv string, field *schema.Field) ([]string, string) {
+	var guards []string
+	path := field.Node.Path()[1:]
+	for i, node := range path[:len(path)-1] {
+		if node.Kind == parse.Ptr {
+			guards = append(guards, fmt.Sprintf("%s.%s != nil", recv, join(path[:i+1], ".")))
+		}
+	}
+	return guards, recv + "." + join(path, ".")
+}
+
+// getAutoFields returns the auto-increment fields of the
+// table.
+func getAutoFields(t *schema.Table) []*schema.Field {
+	var fields []*schema.Field
+	for _, field := range t.Fields {
+		if field.Auto {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+func hasAuto(fields []*schema.Field) bool {
+	for _, field := range fields {
+		if field.Auto {
+			return true
+		}
+	}
+	return false
+}
+
+func containsField(fields []*schema.Field, field *schema.Field) bool {
+	for _, f := range fields {
+		if f.Name == field.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// namedResults returns the results of a method with the
+// error named err and the others blank, such as (_ int, err
+// error), so that the error can be wrapped by a deferred call.
+func namedResults(results string) string {
+	parts := strings.Split(strings.Trim(results, "()"), ", ")
+	for i, part := range parts[:len(parts)-1] {
+		parts[i] = "_ " + part
+	}
+	parts[len(parts)-1] = "err error"
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+// join2 joins the context parameter to the parameters
+// of a method.
+func join2(ctx, params string) string {
+	if params == "" {
+		return strings.TrimSuffix(ctx, ", ")
+	}
+	return ctx + params
+}
+
+func prefixComma(args string) string {
+	if args == "" {
+		return ""
+	}
+	return ", " + args
+}
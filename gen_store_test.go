@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/linchunquan/sqlgen/parse"
+	"github.com/linchunquan/sqlgen/schema"
+)
+
+func TestMatchCode(t *testing.T) {
+	user := &parse.Node{Name: "User"}
+	addr := &parse.Node{Name: "Addr", Kind: parse.Ptr, Parent: user}
+	login := &parse.Node{Name: "Login", Type: "string", Parent: user}
+	zip := &parse.Node{Name: "Zip", Type: "string", Pointer: true, Parent: addr}
+
+	fields := []*schema.Field{
+		{Name: "f_login", Node: login},
+		{Name: "f_addr_zip", Node: zip},
+	}
+
+	want := "db.Equal(v.Login, login) && v.Addr != nil && v.Addr.Zip != nil && db.Equal(*v.Addr.Zip, zip)"
+	if got := getMatchCode(fields); got != want {
+		t.Errorf("Wanted match %s, got %s", want, got)
+	}
+}
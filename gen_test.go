@@ -0,0 +1,147 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/linchunquan/sqlgen/parse"
+	"golang.org/x/tools/go/packages"
+)
+
+// generateAll generates the code of every tagged type of
+// the package in the testdata directory, as with -all, into
+// the testdata/gen package.
+func generateAll(t *testing.T, dir string) string {
+	defer func(pkg, src string, all bool) {
+		*pkgName, *srcPkgName, *allTypes = pkg, src, all
+	}(*pkgName, *srcPkgName, *allTypes)
+	*pkgName = "gen"
+	*srcPkgName = "github.com/linchunquan/sqlgen/testdata/" + dir
+	*allTypes = true
+
+	trees, err := parse.ParseAll("./testdata/" + dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := generate(trees)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := ioutil.ReadAll(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(src)
+}
+
+// typeCheck type checks the generated code as a file of the
+// testdata/gen package, failing the test on any error.
+func typeCheck(t *testing.T, src string) {
+	dir, err := filepath.Abs("testdata/gen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &packages.Config{
+		Mode:    packages.NeedName | packages.NeedTypes | packages.NeedDeps | packages.NeedImports,
+		Overlay: map[string][]byte{filepath.Join(dir, "out.go"): []byte(src)},
+	}
+	pkgs, err := packages.Load(cfg, "./testdata/gen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pkg := range pkgs {
+		for _, err := range pkg.Errors {
+			t.Errorf("Wanted generated code to type check, got %s", err)
+		}
+	}
+}
+
+func TestGeneratePointerTime(t *testing.T) {
+	typeCheck(t, generateAll(t, "pointertime"))
+}
+
+func TestGenerateValuer(t *testing.T) {
+	typeCheck(t, generateAll(t, "valuer"))
+}
+
+func TestGenerateUnsupported(t *testing.T) {
+	typeCheck(t, generateAll(t, "skip"))
+}
+
+func TestGenerateAll(t *testing.T) {
+	src := generateAll(t, "embed")
+	typeCheck(t, src)
+
+	for _, fn := range []string{"func InsertGroup(", "func InsertUser("} {
+		if !strings.Contains(src, fn) {
+			t.Errorf("Wanted %s generated for a tagged struct", fn)
+		}
+	}
+	if strings.Contains(src, "func InsertBase(") {
+		t.Errorf("Wanted no functions generated for the embedded Base")
+	}
+}
+
+func TestGenerateInsert(t *testing.T) {
+	defer func(db string) { *database = db }(*database)
+
+	var tests = []struct {
+		db   string
+		want []string
+	}{
+		{"sqlite", []string{
+			"INSERT INTO notes (\n f_title\n,f_body\n) VALUES (?,?)\n`",
+			"res, err := db.Exec(InsertNoteStmt, args[0], args[2])",
+			"v.ID = id",
+			"args = append(args, a[0], a[2])",
+			"id -= int64(n - 1)\n\t\tfor _, v := range vv[:n] {\n\t\t\tv.ID = id\n",
+			"if _, err := db.Exec(InsertTagStmt, sliceTag(v)...); err != nil {",
+		}},
+		{"mysql", []string{
+			"res, err := db.Exec(InsertNoteStmt, args[0], args[2])",
+			"id, err := res.LastInsertId()\n\t\tif err != nil {\n\t\t\treturn err\n\t\t}\n\t\tfor _, v := range vv[:n] {\n\t\t\tv.ID = id\n",
+			"if _, err := db.Exec(InsertTagStmt, sliceTag(v)...); err != nil {",
+		}},
+		{"postgres", []string{
+			"INSERT INTO notes (\n f_title\n,f_body\n) VALUES ($1,$2)\nRETURNING f_id\n`",
+			"row := db.QueryRow(InsertNoteStmt, args[0], args[2])",
+			"if err := row.Scan(&v.ID); err != nil {",
+			"if err := rows.Scan(&v.ID); err != nil {",
+			"if _, err := db.Exec(InsertTagStmt, sliceTag(v)...); err != nil {",
+		}},
+	}
+
+	for _, test := range tests {
+		*database = test.db
+		src := generateAll(t, "insert")
+		typeCheck(t, src)
+		for _, want := range test.want {
+			if !strings.Contains(src, want) {
+				t.Errorf("Wanted %s insert to contain %s", test.db, want)
+			}
+		}
+	}
+}
+
+func TestGenerateUpdateKeys(t *testing.T) {
+	defer func(keys, store bool) {
+		*updateKeys, *genStore = keys, store
+	}(*updateKeys, *genStore)
+	*updateKeys, *genStore = false, true
+
+	src := generateAll(t, "pointertime")
+	typeCheck(t, src)
+
+	// the key is rejected by the query and by the in-memory
+	// store alike.
+	if n := strings.Count(src, `case EventColumnId:
+			return "", nil, fmt.Errorf("cannot update events key column %s", eventColumns[col])`); n != 1 {
+		t.Errorf("Wanted the update fields query to reject the key, got %d", n)
+	}
+	if n := strings.Count(src, `case EventColumnId:
+			return fmt.Errorf("cannot update events key column %s", eventColumns[col])`); n != 1 {
+		t.Errorf("Wanted the in-memory store to reject the key, got %d", n)
+	}
+}
@@ -24,6 +24,18 @@ const (
 	String
 	Slice
 	Struct
+	Time
+	Valuer
+)
+
+// lifecycle hook methods of a model, called by the
+// generated functions. The Before hooks and AfterScan return
+// an error, which aborts the write or the scan.
+const (
+	BeforeInsert = "BeforeInsert"
+	AfterInsert  = "AfterInsert"
+	BeforeUpdate = "BeforeUpdate"
+	AfterScan    = "AfterScan"
 )
 
 var Types = map[string]uint8{
@@ -45,4 +57,5 @@ var Types = map[string]uint8{
 	"interface{}": Interface,
 	"[]byte":      Bytes,
 	"string":      String,
+	"time.Time":   Time,
 }
@@ -1,11 +1,16 @@
 package parse
 
 type Node struct {
-	Pkg  string // source code package.
-	Name string // source code name.
-	Kind uint8  // source code kind.
-	Type string // source code type.
-	Tags *Tag
+	Pkg     string // source code package.
+	Import  string // source code package import path.
+	Name    string // source code name.
+	Kind    uint8  // source code kind.
+	Type    string // source code type.
+	Tags    *Tag
+	Inline  bool     // embedded struct flattened into its parent.
+	Pointer bool     // source code field is a pointer to Type.
+	Named   string   // source code named type with Type as its underlying type.
+	Hooks   []string // lifecycle hook methods of the root type.
 
 	Parent *Node
 	Nodes  []*Node
@@ -16,6 +21,17 @@ func (n *Node) append(node *Node) {
 	n.Nodes = append(n.Nodes, node)
 }
 
+// HasHook returns true if the root type has the named
+// lifecycle hook method.
+func (n *Node) HasHook(name string) bool {
+	for _, hook := range n.Hooks {
+		if hook == name {
+			return true
+		}
+	}
+	return false
+}
+
 // Walk traverses the node tree, invoking the callback
 // function for each node that is traversed.
 func (n *Node) Walk(fn func(*Node)) {
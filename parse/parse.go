@@ -3,9 +3,14 @@ package parse
 import (
 	"errors"
 	"fmt"
-	"go/ast"
-	"go/parser"
-	"go/token"
+	"go/types"
+	"log"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
 )
 
 var (
@@ -13,100 +18,368 @@ var (
 	ErrTypeInvalid  = errors.New("Cannot convert type to a SQL type.")
 )
 
+// Parse parses the named struct type into a Node tree.
+// The path may be a Go source file, in which case the
+// package containing the file is loaded, or a package
+// import path or directory.
 func Parse(path, name string) (*Node, error) {
+	nodes, err := ParseTypes(path, name)
+	if err != nil {
+		return nil, err
+	}
+	return nodes[0], nil
+}
 
-	var fset = token.NewFileSet()
-	var file, err = parser.ParseFile(fset, path, nil, parser.ParseComments)
+// ParseTypes parses each of the named struct types from
+// the package at path, in the order they are named.
+func ParseTypes(path string, names ...string) ([]*Node, error) {
+	pkg, err := load(path)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, decl := range file.Decls {
-		gen, ok := decl.(*ast.GenDecl)
+	var nodes []*Node
+	for _, name := range names {
+		obj, ok := pkg.Types.Scope().Lookup(name).(*types.TypeName)
 		if !ok {
-			continue
+			return nil, ErrTypeNotFound
 		}
-		spec, ok := gen.Specs[0].(*ast.TypeSpec)
-		if !ok {
+		node, err := parseType(pkg.Types, obj)
+		if err != nil {
+			return nil, typeError(pkg, err)
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// ParseAll parses every struct type that has at least
+// one field with a sql tag. If path is a Go source file
+// only the types declared in that file are parsed. Types
+// embedded in another struct of the package are skipped, as
+// helpers such as a shared Base are not tables of their own.
+func ParseAll(path string) ([]*Node, error) {
+	pkg, err := load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file string
+	if isFile(path) {
+		file, _ = filepath.Abs(path)
+	}
+
+	// collect the candidate types and sort them by their
+	// position in the source so the output is stable.
+	var objs []*types.TypeName
+	scope := pkg.Types.Scope()
+	embedded := embeddedTypes(scope)
+	for _, name := range scope.Names() {
+		obj, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || !hasSqlTags(obj) || embedded[obj] {
 			continue
 		}
-		if spec.Name.String() != name {
+		if file != "" && pkg.Fset.Position(obj.Pos()).Filename != file {
 			continue
 		}
+		objs = append(objs, obj)
+	}
+	sort.Slice(objs, func(i, j int) bool {
+		pi, pj := pkg.Fset.Position(objs[i].Pos()), pkg.Fset.Position(objs[j].Pos())
+		if pi.Filename != pj.Filename {
+			return pi.Filename < pj.Filename
+		}
+		return pi.Offset < pj.Offset
+	})
+
+	var nodes []*Node
+	for _, obj := range objs {
+		node, err := parseType(pkg.Types, obj)
+		if err != nil {
+			return nil, typeError(pkg, err)
+		}
+		nodes = append(nodes, node)
+	}
+	if len(nodes) == 0 {
+		return nil, ErrTypeNotFound
+	}
+	return nodes, nil
+}
+
+// load loads and type checks the package at path. A
+// path ending in .go loads the package containing that
+// file; anything else is passed to the go tool as-is.
+//
+// Type errors are kept in the package rather than returned,
+// so that a stale generated file in the package does not stop
+// it from being regenerated. A parsed field whose type failed
+// to check is reported by buildNode instead.
+func load(path string) (*packages.Package, error) {
+	pattern := path
+	if isFile(path) {
+		pattern = "file=" + path
+	}
+
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedImports |
+			packages.NeedDeps | packages.NeedTypes,
+	}
+	pkgs, err := packages.Load(cfg, pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) == 0 {
+		return nil, fmt.Errorf("cannot find package %s", path)
+	}
+	pkg := pkgs[0]
+	for _, err := range pkg.Errors {
+		if err.Kind != packages.TypeError {
+			return nil, err
+		}
+	}
+	if pkg.Types == nil {
+		return nil, fmt.Errorf("cannot type check package %s", path)
+	}
+	return pkg, nil
+}
+
+// typeError adds the first type error of the package to an
+// error returned while parsing one of its types, since the
+// error is most likely caused by it.
+func typeError(pkg *packages.Package, err error) error {
+	if len(pkg.Errors) == 0 {
+		return err
+	}
+	return fmt.Errorf("%w: %v", err, pkg.Errors[0])
+}
+
+func isFile(path string) bool {
+	return strings.HasSuffix(path, ".go")
+}
 
-		var node = new(Node)
-		node.Name = spec.Name.String()
-		node.Type = spec.Name.String()
-		node.Pkg = file.Name.Name
-		err = buildNodes(node, spec)
-		return node, err
+func parseType(pkg *types.Package, obj *types.TypeName) (*Node, error) {
+	var node = new(Node)
+	node.Name = obj.Name()
+	node.Type = obj.Name()
+	node.Pkg = pkg.Name()
+	node.Import = pkg.Path()
+
+	typ, ok := obj.Type().Underlying().(*types.Struct)
+	if !ok {
+		return nil, ErrTypeInvalid
 	}
+	err := buildNodes(pkg, node, typ, true)
+	node.Hooks = findHooks(obj.Type())
+	return node, err
+}
 
-	return nil, ErrTypeNotFound
+// findHooks returns the lifecycle hook methods of the
+// type, with either a value or pointer receiver. Methods of
+// the same name with another signature are not hooks.
+func findHooks(typ types.Type) []string {
+	var hooks []string
+	for _, name := range []string{BeforeInsert, AfterInsert, BeforeUpdate, AfterScan} {
+		sig := lookupMethod(types.NewPointer(typ), name)
+		if sig == nil || sig.Params().Len() != 0 {
+			continue
+		}
+		if name == AfterInsert {
+			if sig.Results().Len() == 0 {
+				hooks = append(hooks, name)
+			}
+		} else if sig.Results().Len() == 1 && isError(sig.Results().At(0).Type()) {
+			hooks = append(hooks, name)
+		}
+	}
+	return hooks
 }
 
-func buildNodes(parent *Node, spec *ast.TypeSpec) error {
-	ident, ok := spec.Type.(*ast.StructType)
+// hasSqlTags returns true if the type is a struct and
+// at least one of its fields carries a sql tag.
+func hasSqlTags(obj *types.TypeName) bool {
+	typ, ok := obj.Type().Underlying().(*types.Struct)
 	if !ok {
-		return ErrTypeInvalid
+		return false
+	}
+	for i := 0; i < typ.NumFields(); i++ {
+		if _, ok := reflect.StructTag(typ.Tag(i)).Lookup("sql"); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// embeddedTypes returns the types of the scope embedded,
+// directly or by pointer, in a struct type of the scope.
+func embeddedTypes(scope *types.Scope) map[*types.TypeName]bool {
+	embedded := map[*types.TypeName]bool{}
+	for _, name := range scope.Names() {
+		obj, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok {
+			continue
+		}
+		typ, ok := obj.Type().Underlying().(*types.Struct)
+		if !ok {
+			continue
+		}
+		for i := 0; i < typ.NumFields(); i++ {
+			field := typ.Field(i)
+			if !field.Embedded() {
+				continue
+			}
+			ftyp := field.Type()
+			if ptr, ok := ftyp.(*types.Pointer); ok {
+				ftyp = ptr.Elem()
+			}
+			if named, ok := ftyp.(*types.Named); ok && named.Obj().Parent() == scope {
+				embedded[named.Obj()] = true
+			}
+		}
 	}
+	return embedded
+}
 
-	for _, field := range ident.Fields.List {
-		var tag string
-		if field.Tag != nil {
-			tag = field.Tag.Value
+// buildNodes appends a node for each field in the struct
+// to the parent. Unexported fields are only included when
+// the struct is declared in the source package, since the
+// generated code cannot reach them otherwise.
+func buildNodes(pkg *types.Package, parent *Node, typ *types.Struct, local bool) error {
+	for i := 0; i < typ.NumFields(); i++ {
+		field := typ.Field(i)
+		if !field.Exported() && !local {
+			continue
+		}
+		err := buildNode(pkg, parent, field.Type(), field.Name(), typ.Tag(i), field.Embedded())
+		if err != nil {
+			return err
 		}
-		buildNode(parent, field.Type, field.Names[0].Name, tag)
 	}
+	shadow(parent)
 	return nil
 }
 
-func buildNode(parent *Node, expr ast.Expr, name, tag string) error {
-	var err error
+// shadow removes the fields of inlined structs that are
+// not promoted to the outer struct, matching Go's selector
+// rules. A field is promoted from the shallowest depth it is
+// declared at, and not at all if declared more than once at
+// that depth, as the selector is then ambiguous.
+func shadow(parent *Node) {
+	type promotion struct{ depth, count int }
+	found := map[string]*promotion{}
+	var visit func(node *Node, depth int)
+	visit = func(node *Node, depth int) {
+		for _, child := range node.Nodes {
+			if child.Inline {
+				visit(child, depth+1)
+				continue
+			}
+			p := found[child.Name]
+			switch {
+			case p == nil || depth < p.depth:
+				found[child.Name] = &promotion{depth, 1}
+			case depth == p.depth:
+				p.count++
+			}
+		}
+	}
+	visit(parent, 0)
+
+	var prune func(node *Node, depth int)
+	prune = func(node *Node, depth int) {
+		var nodes []*Node
+		for _, child := range node.Nodes {
+			if child.Inline {
+				prune(child, depth+1)
+			} else if p := found[child.Name]; p.depth != depth || p.count != 1 {
+				continue
+			}
+			nodes = append(nodes, child)
+		}
+		node.Nodes = nodes
+	}
+	prune(parent, 0)
+}
+
+func buildNode(pkg *types.Package, parent *Node, typ types.Type, name, tag string, embedded bool) error {
+	tags, err := parseTag(tag)
+	if err != nil {
+		return err
+	}
+	if tags.Skip {
+		return nil
+	}
+	if !validType(typ) {
+		return fmt.Errorf("%s is not a valid type", name)
+	}
+
+	// embedded structs are flattened into the parent
+	// unless the tag explicitly opts out.
+	inline := embedded && (tags.Inline == nil || *tags.Inline)
+
+	switch t := typ.(type) {
+	case *types.Basic:
+		node := &Node{
+			Name: name,
+			Type: t.Name(),
+			Kind: Types[t.Name()],
+			Tags: tags,
+		}
+		parent.append(node)
+		return nil
 
-	switch ident := expr.(type) {
-	case *ast.Ident:
-		if ident.Obj == nil {
+	case *types.Named:
+		if isValuer(t, false) {
 			node := &Node{
-				Name: name,
-				Type: ident.Name,
-				Kind: Types[ident.Name],
+				Name:   name,
+				Type:   typeString(pkg, t),
+				Kind:   Valuer,
+				Tags:   tags,
+				Pkg:    t.Obj().Pkg().Name(),
+				Import: t.Obj().Pkg().Path(),
 			}
-			node.Tags, err = parseTag(tag)
-			if err != nil {
-				return err
+			parent.append(node)
+			return nil
+		}
+		if isTime(t) {
+			node := &Node{Name: name, Type: "time.Time", Kind: Time, Tags: tags}
+			parent.append(node)
+			return nil
+		}
+		if basic, ok := t.Underlying().(*types.Basic); ok {
+			node := &Node{
+				Name:   name,
+				Type:   basic.Name(),
+				Kind:   Types[basic.Name()],
+				Tags:   tags,
+				Pkg:    t.Obj().Pkg().Name(),
+				Import: t.Obj().Pkg().Path(),
+				Named:  typeString(pkg, t),
 			}
 			parent.append(node)
 			return nil
 		}
-		spec, ok := ident.Obj.Decl.(*ast.TypeSpec)
+		inner, ok := t.Underlying().(*types.Struct)
 		if !ok {
-			goto invalidType
+			break
 		}
 		node := &Node{
-			Name: name,
-			Type: ident.Name,
-			Kind: Struct,
-		}
-		node.Tags, err = parseTag(tag)
-		if err != nil {
-			return err
+			Name:   name,
+			Type:   typeString(pkg, t),
+			Kind:   Struct,
+			Tags:   tags,
+			Inline: inline,
+			Pkg:    t.Obj().Pkg().Name(),
+			Import: t.Obj().Pkg().Path(),
 		}
 		parent.append(node)
-		return buildNodes(node, spec)
+		return buildNodes(pkg, node, inner, t.Obj().Pkg() == pkg)
 
-	case *ast.ArrayType:
-		if ident.Len != nil {
-			goto invalidType
-		}
+	case *types.Slice:
 		node := &Node{
 			Name: name,
 			Kind: Slice,
-			Type: fmt.Sprintf("[]%s", ident.Elt),
-		}
-		node.Tags, err = parseTag(tag)
-		if err != nil {
-			return err
+			Type: typeString(pkg, t),
+			Tags: tags,
 		}
 		if node.Type == "[]byte" {
 			node.Kind = Bytes
@@ -114,40 +387,173 @@ func buildNode(parent *Node, expr ast.Expr, name, tag string) error {
 		parent.append(node)
 		return nil
 
-	case *ast.MapType:
-		type_ := fmt.Sprintf("map[%s]%s", ident.Key, ident.Value)
-		node := &Node{Name: name, Type: type_, Kind: Map}
-		node.Tags, err = parseTag(tag)
-		if err != nil {
-			return err
-		}
+	case *types.Map:
+		node := &Node{Name: name, Type: typeString(pkg, t), Kind: Map, Tags: tags}
 		parent.append(node)
 		return nil
 
-	case *ast.StarExpr:
-		innerIdent, ok := ident.X.(*ast.Ident)
-		if !ok {
-			goto invalidType
+	case *types.Interface:
+		if !t.Empty() {
+			break
 		}
-		if innerIdent.Obj == nil || innerIdent.Obj.Decl == nil {
-			goto invalidType
+		node := &Node{Name: name, Type: "interface{}", Kind: Interface, Tags: tags}
+		parent.append(node)
+		return nil
+
+	case *types.Pointer:
+		// pointers to basic types are stored in nullable
+		// columns, with nil written and read as NULL.
+		if basic, ok := t.Elem().(*types.Basic); ok {
+			node := &Node{
+				Name:    name,
+				Type:    basic.Name(),
+				Kind:    Types[basic.Name()],
+				Tags:    tags,
+				Pointer: true,
+			}
+			parent.append(node)
+			return nil
 		}
-		spec, ok := innerIdent.Obj.Decl.(*ast.TypeSpec)
+		named, ok := t.Elem().(*types.Named)
 		if !ok {
-			goto invalidType
+			break
 		}
-		node := &Node{Name: name, Type: innerIdent.Name, Kind: Ptr}
-		node.Tags, err = parseTag(tag)
-		if err != nil {
-			return err
+		if isValuer(named, true) {
+			node := &Node{
+				Name:    name,
+				Type:    typeString(pkg, named),
+				Kind:    Valuer,
+				Tags:    tags,
+				Pkg:     named.Obj().Pkg().Name(),
+				Import:  named.Obj().Pkg().Path(),
+				Pointer: true,
+			}
+			parent.append(node)
+			return nil
 		}
-		if node.Tags.Skip {
+		if isTime(named) {
+			node := &Node{Name: name, Type: "time.Time", Kind: Time, Tags: tags, Pointer: true}
+			parent.append(node)
+			return nil
+		}
+		if basic, ok := named.Underlying().(*types.Basic); ok {
+			node := &Node{
+				Name:    name,
+				Type:    basic.Name(),
+				Kind:    Types[basic.Name()],
+				Tags:    tags,
+				Pkg:     named.Obj().Pkg().Name(),
+				Import:  named.Obj().Pkg().Path(),
+				Named:   typeString(pkg, named),
+				Pointer: true,
+			}
+			parent.append(node)
 			return nil
 		}
+		inner, ok := named.Underlying().(*types.Struct)
+		if !ok {
+			break
+		}
+		node := &Node{
+			Name:   name,
+			Type:   typeString(pkg, named),
+			Kind:   Ptr,
+			Tags:   tags,
+			Inline: inline,
+			Pkg:    named.Obj().Pkg().Name(),
+			Import: named.Obj().Pkg().Path(),
+		}
 		parent.append(node)
-		return buildNodes(node, spec)
+		return buildNodes(pkg, node, inner, named.Obj().Pkg() == pkg)
+	}
+
+	log.Printf("skip field %s of unsupported type %s\n", name, typ)
+	return nil
+}
+
+// validType returns false if the type, or the type it is
+// made of, failed to type check.
+func validType(typ types.Type) bool {
+	switch t := typ.(type) {
+	case *types.Basic:
+		return t.Kind() != types.Invalid
+	case *types.Named:
+		if _, ok := t.Underlying().(*types.Struct); ok {
+			return true
+		}
+		return validType(t.Underlying())
+	case *types.Pointer:
+		return validType(t.Elem())
+	case *types.Slice:
+		return validType(t.Elem())
+	case *types.Array:
+		return validType(t.Elem())
+	case *types.Map:
+		return validType(t.Key()) && validType(t.Elem())
+	}
+	return true
+}
+
+// isValuer returns true if the type implements both the
+// sql.Scanner and driver.Valuer interfaces, in which case
+// the value is handed to the driver as-is. The methods are
+// matched by signature so that database/sql need not be
+// loaded alongside the source package. The field is scanned
+// through a pointer, but its value is passed as declared, so
+// Value must be in the method set of the type itself unless
+// the field is a pointer.
+func isValuer(typ *types.Named, pointer bool) bool {
+	scan := lookupMethod(types.NewPointer(typ), "Scan")
+	if scan == nil || scan.Params().Len() != 1 || scan.Results().Len() != 1 {
+		return false
+	}
+	if !isEmptyInterface(scan.Params().At(0).Type()) || !isError(scan.Results().At(0).Type()) {
+		return false
+	}
+	var valueType types.Type = typ
+	if pointer {
+		valueType = types.NewPointer(typ)
+	}
+	value := lookupMethod(valueType, "Value")
+	if value == nil || value.Params().Len() != 0 || value.Results().Len() != 2 {
+		return false
+	}
+	return isEmptyInterface(value.Results().At(0).Type()) && isError(value.Results().At(1).Type())
+}
+
+func lookupMethod(typ types.Type, name string) *types.Signature {
+	obj, _, _ := types.LookupFieldOrMethod(typ, false, nil, name)
+	fn, ok := obj.(*types.Func)
+	if !ok {
+		return nil
 	}
+	return fn.Type().(*types.Signature)
+}
+
+func isEmptyInterface(typ types.Type) bool {
+	iface, ok := typ.Underlying().(*types.Interface)
+	return ok && iface.Empty()
+}
 
-invalidType:
-	return fmt.Errorf("%s is not a valid type", name)
+func isError(typ types.Type) bool {
+	return types.Identical(typ, types.Universe.Lookup("error").Type())
+}
+
+// isTime returns true if the type is time.Time, which is
+// stored in a single column rather than as a nested struct.
+func isTime(typ *types.Named) bool {
+	obj := typ.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == "time" && obj.Name() == "Time"
+}
+
+// typeString returns the type as it would be written in
+// the source package, qualifying types from any other
+// package with that package's name.
+func typeString(pkg *types.Package, typ types.Type) string {
+	return types.TypeString(typ, func(other *types.Package) string {
+		if other == pkg {
+			return ""
+		}
+		return other.Name()
+	})
 }
@@ -0,0 +1,144 @@
+package parse
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// edgeNames returns the names of the edge nodes of the
+// tree, joined with the names of their parents.
+func edgeNames(tree *Node) []string {
+	var names []string
+	for _, edge := range tree.Edges() {
+		name := edge.Name
+		for node := edge.Parent; node != tree; node = node.Parent {
+			name = node.Name + "." + name
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestParseOtherFiles(t *testing.T) {
+	tree, err := Parse("../testdata/embed", "User")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"Base.ID",
+		"Base.Created",
+		"Audit.Editor",
+		"Login",
+		"Status",
+		"Address.City",
+		"Address.Zip",
+	}
+	if got := edgeNames(tree); !reflect.DeepEqual(got, want) {
+		t.Errorf("Wanted fields %v, got %v", want, got)
+	}
+
+	base, audit, status, address := tree.Nodes[0], tree.Nodes[1], tree.Nodes[3], tree.Nodes[4]
+	if base.Kind != Struct || !base.Inline || base.Type != "Base" {
+		t.Errorf("Wanted Base inlined from another file, got %+v", base)
+	}
+	if audit.Kind != Struct || !audit.Inline || audit.Type != "other.Audit" {
+		t.Errorf("Wanted other.Audit inlined from another package, got %+v", audit)
+	}
+	if status.Kind != Int || status.Named != "other.Status" || status.Import != "github.com/linchunquan/sqlgen/testdata/embed/other" {
+		t.Errorf("Wanted other.Status stored as an int, got %+v", status)
+	}
+	if address.Kind != Struct || address.Inline || address.Type != "other.Address" {
+		t.Errorf("Wanted other.Address nested from another package, got %+v", address)
+	}
+}
+
+func TestParseStale(t *testing.T) {
+	// user_sql.go no longer type checks, which must not
+	// stop the types it was generated from being parsed.
+	tree, err := Parse("../testdata/stale/user.go", "User")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := []string{"ID", "Login"}, edgeNames(tree); !reflect.DeepEqual(got, want) {
+		t.Errorf("Wanted fields %v, got %v", want, got)
+	}
+
+	// a field of a type that does not type check is
+	// still an error.
+	_, err = Parse("../testdata/stale", "Account")
+	if err == nil {
+		t.Fatal("Wanted error parsing a field of an undeclared type")
+	}
+	if errors.Is(err, ErrTypeNotFound) {
+		t.Errorf("Wanted the type error, got %v", err)
+	}
+}
+
+func TestParseAllEmbedded(t *testing.T) {
+	trees, err := ParseAll("../testdata/embed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, tree := range trees {
+		got = append(got, tree.Type)
+	}
+	// Base is embedded by Group and User, and other.Audit
+	// is declared in another package.
+	if want := []string{"Group", "User"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Wanted types %v, got %v", want, got)
+	}
+}
+
+func TestParseValuer(t *testing.T) {
+	tree, err := Parse("../testdata/valuer", "Item")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a Value method with a pointer receiver is only in
+	// the method set of a pointer field.
+	code, codePtr, tag := tree.Nodes[1], tree.Nodes[2], tree.Nodes[3]
+	if code.Kind == Valuer {
+		t.Errorf("Wanted Code with a pointer receiver Value not stored as a Valuer, got %+v", code)
+	}
+	if codePtr.Kind != Valuer || !codePtr.Pointer {
+		t.Errorf("Wanted *Code stored as a Valuer, got %+v", codePtr)
+	}
+	if tag.Kind != Valuer {
+		t.Errorf("Wanted Tag with a value receiver Value stored as a Valuer, got %+v", tag)
+	}
+}
+
+func TestParsePromoted(t *testing.T) {
+	var tests = []struct {
+		typ  string
+		want []string
+	}{
+		{"X", []string{"A.Name", "B.Email", "Login"}},
+		{"Y", []string{"C.A.Name", "D.ID"}},
+		{"Z", []string{"C.A.Name", "E.B.Email"}},
+	}
+	for _, test := range tests {
+		tree, err := Parse("../testdata/promote", test.typ)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := edgeNames(tree); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Wanted %s fields %v, got %v", test.typ, test.want, got)
+		}
+	}
+}
+
+func TestParseUnsupported(t *testing.T) {
+	tree, err := Parse("../testdata/skip", "Job")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"ID", "Name"}
+	if got := edgeNames(tree); !reflect.DeepEqual(got, want) {
+		t.Errorf("Wanted fields %v, got %v", want, got)
+	}
+}
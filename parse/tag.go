@@ -23,12 +23,43 @@ type Tag struct {
 	Index   string `yaml:"index"`
 	Unique  string `yaml:"unique"`
 	Size    int    `yaml:"size"`
+	Precision int `yaml:"precision"`
+	TZ      bool   `yaml:"tz"`
+	NotNull bool   `yaml:"notnull"`
+	Default string `yaml:"default"`
 	Skip    bool   `yaml:"skip"`
 	Encode  string `yaml:"encode"`
 	Foreign string `yaml:"fk"`
 	Many    bool   `yaml:"many"`
 	ForeignGroup string `yaml:"fkGroup"`
 
+	// comma-separated list of allowed values, quoted so
+	// the commas are not read as separate tag entries.
+	Enum string `yaml:"enum"`
+
+	// sort direction of the column, asc or desc. The tagged
+	// columns, in field order, are the table's default order.
+	Order string `yaml:"order"`
+
+	// integer column incremented by every update, which
+	// only succeeds if the row still has the version read.
+	Version bool `yaml:"version"`
+
+	// timestamp or bool column marking a row as deleted,
+	// which the generated deletes set rather than removing
+	// the row, and the generated selects exclude.
+	SoftDelete bool `yaml:"softdelete"`
+
+	// time.Time columns set to the current time when the
+	// generated functions insert a row, and for the update
+	// time also when they update it.
+	AutoCreateTime bool `yaml:"autoCreateTime"`
+	AutoUpdateTime bool `yaml:"autoUpdateTime"`
+
+	// flatten an embedded struct into its parent without
+	// a name prefix; defaults to true for embedded fields.
+	Inline *bool `yaml:"inline"`
+
 	// customize the table name
 	TableName string `yaml:"tableName"`
 }
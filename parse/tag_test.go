@@ -41,6 +41,42 @@ var tagTests = []struct {
 		`sql:"fk: id@users"`,
 		&Tag{Foreign: "id@users"},
 	},
+	{
+		`sql:"precision: 6, tz: true"`,
+		&Tag{Precision: 6, TZ: true},
+	},
+	{
+		`sql:"enum: 'open,closed'"`,
+		&Tag{Enum: "open,closed"},
+	},
+	{
+		`sql:"notnull: true, default: 0"`,
+		&Tag{NotNull: true, Default: "0"},
+	},
+	{
+		`sql:"default: CURRENT_TIMESTAMP"`,
+		&Tag{Default: "CURRENT_TIMESTAMP"},
+	},
+	{
+		`sql:"inline: false"`,
+		&Tag{Inline: new(bool)},
+	},
+	{
+		`sql:"order: desc"`,
+		&Tag{Order: "desc"},
+	},
+	{
+		`sql:"version: true"`,
+		&Tag{Version: true},
+	},
+	{
+		`sql:"softdelete: true"`,
+		&Tag{SoftDelete: true},
+	},
+	{
+		`sql:"autoCreateTime: true"`,
+		&Tag{AutoCreateTime: true},
+	},
 }
 
 func TestParseTag(t *testing.T) {
@@ -11,6 +11,10 @@ import (
 
 type base struct {
 	Dialect Dialect
+
+	// returning is true if the database supports
+	// INSERT ... RETURNING.
+	returning bool
 }
 
 // Table returns a SQL statement to create the table.
@@ -30,6 +34,12 @@ func (b *base) Table(t *Table) string {
 	// flush the tab writer to write to the buffer
 	tab.Flush()
 
+	// a composite primary key cannot be declared inline
+	// on the columns, so it is added as a table constraint.
+	if len(t.Primary) > 1 {
+		fmt.Fprintf(buf, "\n,%s (%s)", b.Dialect.Token(PRIMARY_KEY), b.columns(nil, t.Primary, true, false, false))
+	}
+
 	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s\n);", t.Name, buf.String())
 }
 
@@ -64,33 +74,257 @@ func (b *base) Insert(t *Table) string {
 		}
 	}
 
-	return fmt.Sprintf("INSERT INTO %s (%s\n) VALUES (%s)", t.Name, b.columns(nil, fields, false, false, false), strings.Join(params, ","))
+	var returning []*Field
+	if b.Dialect.Returning() {
+		for _, field := range t.Fields {
+			if field.Auto {
+				returning = append(returning, field)
+			}
+		}
+	}
+
+	if len(returning) == 0 {
+		return fmt.Sprintf("INSERT INTO %s (%s\n) VALUES (%s)", t.Name, b.columns(nil, fields, false, false, false), strings.Join(params, ","))
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s\n) VALUES (%s)\nRETURNING %s", t.Name, b.columns(nil, fields, false, false, false), strings.Join(params, ","), b.columns(nil, returning, true, false, false))
+}
+
+// InsertBatch returns the start of a multi-row INSERT
+// statement, up to the VALUES keyword. The rows of values
+// are appended when the statement is executed, since the
+// number of rows is only known then.
+func (b *base) InsertBatch(t *Table) string {
+	var fields []*Field
+	for _, field := range t.Fields {
+		if !field.Auto {
+			fields = append(fields, field)
+		}
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s\n) VALUES ", t.Name, b.columns(nil, fields, false, false, false))
+}
+
+// Upsert returns an INSERT statement that updates the
+// existing row when it conflicts on the given fields.
+func (b *base) Upsert(t *Table, fields []*Field) string {
+	insert, update := UpsertFields(t, fields)
+
+	var params []string
+	for i := range insert {
+		params = append(params, b.Dialect.Param(i))
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "INSERT INTO %s (%s\n) VALUES (%s)\nON CONFLICT (%s) DO ", t.Name, b.columns(nil, insert, false, false, false), strings.Join(params, ","), b.columns(nil, fields, true, false, false))
+	version := VersionField(t)
+	if len(update) == 0 && version == nil {
+		buf.WriteString("NOTHING")
+		return buf.String()
+	}
+	buf.WriteString("UPDATE SET")
+	for i, field := range update {
+		if i == 0 {
+			buf.WriteString("\n ")
+		} else {
+			buf.WriteString("\n,")
+		}
+		fmt.Fprintf(&buf, "%s=excluded.%s", field.Name, field.Name)
+	}
+
+	// the version of the conflicting row is incremented,
+	// qualified by the table as excluded is also in scope,
+	// and read back when the dialect supports RETURNING.
+	if version != nil {
+		if len(update) == 0 {
+			buf.WriteString("\n ")
+		} else {
+			buf.WriteString("\n,")
+		}
+		fmt.Fprintf(&buf, "%s=%s.%s+1", version.Name, t.Name, version.Name)
+		if b.Dialect.Returning() {
+			fmt.Fprintf(&buf, "\nRETURNING %s", version.Name)
+		}
+	}
+	return buf.String()
+}
+
+// SelectVersion returns a SQL statement selecting the
+// version of the row matching the fields, which an upsert
+// reads back when the dialect does not support RETURNING.
+func (b *base) SelectVersion(t *Table, fields []*Field) string {
+	return fmt.Sprintf("SELECT %s\nFROM %s %s", VersionField(t).Name, t.Name, b.clause(fields, 0))
 }
 
 func (b *base) Update(t *Table, fields []*Field) string {
-	return fmt.Sprintf("UPDATE %s SET %s %s", t.Name, b.columns(nil, t.Fields, false, true, false), b.clause(fields, len(t.Fields)))
+	return b.UpdateColumns(t, t.Fields, fields)
 }
 
+// UpdateColumns returns a SQL statement updating only the
+// set columns of the rows matching the fields. If the table
+// has a version field it is incremented rather than set, and
+// the row is only updated if it still has the version given
+// by the last parameter.
+func (b *base) UpdateColumns(t *Table, set, fields []*Field) string {
+	version := VersionField(t)
+	if version == nil {
+		return fmt.Sprintf("UPDATE %s SET %s %s", t.Name, b.columns(nil, set, false, true, false), b.clause(fields, len(set)))
+	}
+
+	var params []*Field
+	for _, field := range set {
+		if field.Name != version.Name {
+			params = append(params, field)
+		}
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "UPDATE %s SET %s", t.Name, b.columns(nil, params, false, true, false))
+	if len(params) != 0 {
+		buf.WriteString("\n,")
+	} else {
+		buf.WriteString("\n ")
+	}
+	fmt.Fprintf(&buf, "%s=%s+1 %s", version.Name, version.Name, b.clause(fields, len(params)))
+	fmt.Fprintf(&buf, "\nAND %s=%s", version.Name, b.Dialect.Param(len(params)+len(fields)))
+	return buf.String()
+}
+
+// Delete returns a SQL statement deleting the rows matching
+// the fields. If the table has a soft delete field the rows
+// are marked as deleted instead, keeping the time of rows
+// that were already deleted.
 func (b *base) Delete(t *Table, fields []*Field) string {
+	if field := SoftDeleteField(t); field != nil {
+		marker := "CURRENT_TIMESTAMP"
+		if field.Type == BOOLEAN {
+			marker = "TRUE"
+		}
+		return fmt.Sprintf("UPDATE %s SET %s=%s %s", t.Name, field.Name, marker, b.where(t, fields, 0))
+	}
 	return fmt.Sprintf("DELETE FROM %s %s", t.Name, b.clause(fields, 0))
 }
 
 func (b *base) Select(t *Table, fields []*Field) string {
-	return fmt.Sprintf("SELECT %s\nFROM %s %s", b.columns(t, t.Fields, false, false, false), t.Name, b.clause(fields, 0))
+	return fmt.Sprintf("SELECT %s\nFROM %s %s%s", b.columns(t, t.Fields, false, false, false), t.Name, b.where(t, fields, 0), b.order(t))
 }
 
 func (b *base) SelectRange(t *Table, fields []*Field) string {
-	return fmt.Sprintf("SELECT %s\nFROM %s %s\nLIMIT %s OFFSET %s", b.columns(t, t.Fields, false, false, false), t.Name, b.clause(fields, 0), b.Dialect.Param(len(fields)), b.Dialect.Param(len(fields)+1))
+	return fmt.Sprintf("SELECT %s\nFROM %s %s%s\nLIMIT %s OFFSET %s", b.columns(t, t.Fields, false, false, false), t.Name, b.where(t, fields, 0), b.order(t), b.Dialect.Param(len(fields)), b.Dialect.Param(len(fields)+1))
+}
+
+// SelectSorted returns a SQL statement selecting the rows
+// matching the fields, with a %s verb in place of the sort
+// column and direction, followed by the primary key to break
+// ties. When ranged is true the statement is limited as in
+// SelectRange.
+func (b *base) SelectSorted(t *Table, fields []*Field, ranged bool) string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "SELECT %s\nFROM %s %s\nORDER BY %%s", b.columns(t, t.Fields, false, false, false), t.Name, b.where(t, fields, 0))
+	if len(t.Primary) != 0 {
+		fmt.Fprintf(&buf, ",%s", b.columns(nil, t.Primary, true, false, false))
+	}
+	if ranged {
+		fmt.Fprintf(&buf, "\nLIMIT %s OFFSET %s", b.Dialect.Param(len(fields)), b.Dialect.Param(len(fields)+1))
+	}
+	return buf.String()
+}
+
+// helper function to generate the ORDER BY clause of the
+// table's default order, if it has one.
+func (b *base) order(t *Table) string {
+	var parts []string
+	for _, field := range OrderFields(t) {
+		if field.Order == "DESC" {
+			parts = append(parts, field.Name+" DESC")
+		} else {
+			parts = append(parts, field.Name)
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "\nORDER BY " + strings.Join(parts, ",")
+}
+
+// SelectAfter returns a SQL statement selecting a page of
+// rows matching the fields, ordered by the primary key. When
+// after is true the page starts after the key given by the
+// parameters following those of the fields.
+func (b *base) SelectAfter(t *Table, fields []*Field, after bool) string {
+	var buf bytes.Buffer
+	where := b.where(t, fields, 0)
+	fmt.Fprintf(&buf, "SELECT %s\nFROM %s %s", b.columns(t, t.Fields, false, false, false), t.Name, where)
+
+	pos := len(fields)
+	if after {
+		var params []string
+		for i := range t.Primary {
+			params = append(params, b.Dialect.Param(pos+i))
+		}
+		if where == "" {
+			buf.WriteString("\nWHERE ")
+		} else {
+			buf.WriteString("\nAND ")
+		}
+		keys := b.columns(nil, t.Primary, true, false, false)
+		if len(t.Primary) == 1 {
+			fmt.Fprintf(&buf, "%s > %s", keys, params[0])
+		} else {
+			fmt.Fprintf(&buf, "(%s) > (%s)", keys, strings.Join(params, ","))
+		}
+		pos += len(t.Primary)
+	}
+
+	fmt.Fprintf(&buf, "\nORDER BY %s\nLIMIT %s", b.columns(nil, t.Primary, true, false, false), b.Dialect.Param(pos))
+	return buf.String()
+}
+
+// SelectQuery returns the SQL statement selecting the
+// table's columns, without a WHERE or ORDER BY clause, which
+// the generated query builders complete at runtime. Unlike
+// the other selects it does not exclude soft deleted rows,
+// which the query builders do.
+func (b *base) SelectQuery(t *Table) string {
+	return fmt.Sprintf("SELECT %s\nFROM %s", b.columns(t, t.Fields, false, false, false), t.Name)
 }
 
 func (b *base) SelectCount(t *Table, fields []*Field) string {
-	return fmt.Sprintf("SELECT count(1)\nFROM %s %s", t.Name, b.clause(fields, 0))
+	return fmt.Sprintf("SELECT count(1)\nFROM %s %s", t.Name, b.where(t, fields, 0))
 }
 
 func (b *base) SelectByUniqueIndex(t *Table, fields []*Field, index *Index) string{
 	return ""
 }
 
+// Returning returns true if generated keys are read with
+// INSERT ... RETURNING rather than sql.Result.LastInsertId.
+func (b *base) Returning() bool {
+	return b.returning
+}
+
+// FirstInsertId returns true if the LastInsertId of a
+// multi-row insert is the key of its first row, rather than
+// of its last row as on sqlite.
+func (b *base) FirstInsertId() bool {
+	return false
+}
+
+// MaxParams returns the maximum number of bind parameters
+// in a single statement. SQLite before 3.32 allows 999.
+func (b *base) MaxParams() int {
+	return 999
+}
+
+// ErrorFunc returns the name of the db function
+// translating the errors of the driver.
+func (b *base) ErrorFunc() string {
+	return "db.SQLiteError"
+}
+
+// Runtime returns the name of the db package's Dialect
+// of the database, used by the statements built at run time.
+func (b *base) Runtime() string {
+	return "db.SQLite"
+}
+
 // Param returns the parameters symbol used in prepared
 // sql statements.
 func (b *base) Param(i int) string {
@@ -111,6 +345,11 @@ func (b *base) Column(f *Field) string {
 		return "BLOB"
 	case VARCHAR:
 		return "TEXT"
+	case TIMESTAMP:
+		// sqlite has no time storage class, but the
+		// declared type tells the driver to parse the
+		// text value back into a time.Time.
+		return "DATETIME"
 	default:
 		return "TEXT"
 	}
@@ -123,6 +362,12 @@ func (b *base) Token(v int) (_ string) {
 		return "AUTOINCREMENT"
 	case PRIMARY_KEY:
 		return "PRIMARY KEY"
+	case NOT_NULL:
+		return "NOT NULL"
+	case DEFAULT:
+		return "DEFAULT"
+	case CHECK:
+		return "CHECK"
 	default:
 		return
 	}
@@ -173,7 +418,19 @@ func (b *base) columnw(w io.Writer, table *Table, fields []*Field, inline, assig
 		io.WriteString(w, "\t")
 		io.WriteString(w, b.Dialect.Column(field))
 
-		if field.Primary {
+		if field.NotNull {
+			io.WriteString(w, " ")
+			io.WriteString(w, b.Dialect.Token(NOT_NULL))
+		}
+
+		if field.Default != "" {
+			io.WriteString(w, " ")
+			io.WriteString(w, b.Dialect.Token(DEFAULT))
+			io.WriteString(w, " ")
+			io.WriteString(w, defaultValue(field))
+		}
+
+		if field.Primary && (table == nil || len(table.Primary) < 2) {
 			io.WriteString(w, " ")
 			io.WriteString(w, b.Dialect.Token(PRIMARY_KEY))
 		}
@@ -182,7 +439,64 @@ func (b *base) columnw(w io.Writer, table *Table, fields []*Field, inline, assig
 			io.WriteString(w, " ")
 			io.WriteString(w, b.Dialect.Token(AUTO_INCREMENT))
 		}
+
+		if len(field.Enum) != 0 && b.Dialect.Token(CHECK) != "" {
+			io.WriteString(w, " ")
+			io.WriteString(w, b.Dialect.Token(CHECK))
+			io.WriteString(w, " (")
+			io.WriteString(w, field.Name)
+			io.WriteString(w, " IN (")
+			io.WriteString(w, enumValues(field))
+			io.WriteString(w, "))")
+		}
+	}
+}
+
+// helper function to generate the default value of a
+// column. Defaults for text columns are quoted as SQL
+// literals, anything else, such as CURRENT_TIMESTAMP,
+// is written as-is.
+func defaultValue(field *Field) string {
+	switch field.Type {
+	case VARCHAR, MEDIUMTEXT, LONGTEXT:
+		return "'" + strings.Replace(field.Default, "'", "''", -1) + "'"
+	default:
+		return field.Default
+	}
+}
+
+// helper function to generate the comma separated list
+// of allowed values for an enum column. String values
+// are quoted as SQL literals.
+func enumValues(field *Field) string {
+	var values []string
+	for _, value := range field.Enum {
+		if field.Type == VARCHAR {
+			value = "'" + strings.Replace(value, "'", "''", -1) + "'"
+		}
+		values = append(values, value)
+	}
+	return strings.Join(values, ",")
+}
+
+// helper function to generate the Where clause of a
+// SQL statement selecting rows, which excludes the rows
+// marked as deleted if the table has a soft delete field.
+func (b *base) where(t *Table, fields []*Field, pos int) string {
+	clause := b.clause(fields, pos)
+	field := SoftDeleteField(t)
+	if field == nil {
+		return clause
+	}
+
+	cond := field.Name + " IS NULL"
+	if field.Type == BOOLEAN {
+		cond = field.Name + "=FALSE"
+	}
+	if clause == "" {
+		return "\nWHERE " + cond
 	}
+	return clause + "\nAND " + cond
 }
 
 // helper function to generate the Where clause
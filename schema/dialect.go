@@ -18,14 +18,26 @@ type Dialect interface {
 	Foreign(*Table, *Foreign) string
 	Column(*Field) string
 	Insert(*Table) string
+	InsertBatch(*Table) string
+	Upsert(*Table, []*Field) string
 	Update(*Table, []*Field) string
+	UpdateColumns(*Table, []*Field, []*Field) string
 	Delete(*Table, []*Field) string
 	Select(*Table, []*Field) string
 	SelectCount(*Table, []*Field) string
 	SelectRange(*Table, []*Field) string
+	SelectAfter(*Table, []*Field, bool) string
+	SelectSorted(*Table, []*Field, bool) string
+	SelectQuery(*Table) string
 	SelectByUniqueIndex(t *Table, fields []*Field, index *Index) string
+	SelectVersion(*Table, []*Field) string
 	Param(int) string
 	Token(int) string
+	Returning() bool
+	FirstInsertId() bool
+	MaxParams() int
+	ErrorFunc() string
+	Runtime() string
 }
 
 func New(dialect int) Dialect {
@@ -38,3 +50,15 @@ func New(dialect int) Dialect {
 		return newSqlite()
 	}
 }
+
+// EnableReturning opts a sqlite (3.35+) or mysql dialect,
+// when connected to MariaDB 10.5+, into reading generated
+// keys with INSERT ... RETURNING. Postgres always uses it.
+func EnableReturning(d Dialect) {
+	switch d := d.(type) {
+	case *sqlite:
+		d.returning = true
+	case *mysql:
+		d.returning = true
+	}
+}
@@ -1,8 +1,10 @@
 package schema
 
 import (
+	"bytes"
 	"fmt"
 	"log"
+	"strings"
 )
 
 type mysql struct {
@@ -15,7 +17,6 @@ func newMysql() Dialect {
 	return d
 }
 
-//before adding not null
 func (d *mysql) Column(f *Field) (_ string) {
 	switch f.Type {
 	case INTEGER:
@@ -35,6 +36,11 @@ func (d *mysql) Column(f *Field) (_ string) {
 	case LONGTEXT:
 		return "LONGTEXT"
 	case VARCHAR:
+		// mysql has a native type for string
+		// enums in place of a CHECK constraint.
+		if len(f.Enum) != 0 {
+			return fmt.Sprintf("ENUM(%s)", enumValues(f))
+		}
 		// assigns an arbitrary size if
 		// none is provided.
 		size := f.Size
@@ -42,42 +48,22 @@ func (d *mysql) Column(f *Field) (_ string) {
 			size = 512
 		}
 		return fmt.Sprintf("VARCHAR(%d)", size)
-	default:
-		return
-	}
-}
-/*
-func (d *mysql) Column(f *Field) (_ string) {
-	switch f.Type {
-	case INTEGER:
-		return "INTEGER NOT NULL DEFAULT 0"
-	case LONG:
-		if (f.Primary){
-			return "BIGINT"
+	case TIMESTAMP:
+		// mysql converts TIMESTAMP values to and from
+		// UTC using the session time zone, DATETIME
+		// values are stored as given.
+		typ := "DATETIME"
+		if f.TZ {
+			typ = "TIMESTAMP"
 		}
-		return "BIGINT NOT NULL DEFAULT 0"
-	case FLOAT:
-		return "FLOAT NOT NULL DEFAULT 0"
-	case DOUBLE:
-		return "DOUBLE NOT NULL DEFAULT 0"
-	case BOOLEAN:
-		return "BOOLEAN  NOT NULL DEFAULT 0"
-	case BLOB:
-		return "MEDIUMBLOB"
-	case MEDIUMTEXT:
-		return "MEDIUMTEXT"
-	case VARCHAR:
-		// assigns an arbitrary size if
-		// none is provided.
-		size := f.Size
-		if size == 0 {
-			size = 512
+		if f.Precision != 0 {
+			typ = fmt.Sprintf("%s(%d)", typ, f.Precision)
 		}
-		return fmt.Sprintf("VARCHAR(%d) NOT NULL DEFAULT ''", size)
+		return typ
 	default:
 		return
 	}
-}*/
+}
 
 func (d *mysql) Token(v int) (_ string) {
 	switch v {
@@ -85,6 +71,14 @@ func (d *mysql) Token(v int) (_ string) {
 		return "AUTO_INCREMENT"
 	case PRIMARY_KEY:
 		return "PRIMARY KEY"
+	case NOT_NULL:
+		return "NOT NULL"
+	case DEFAULT:
+		return "DEFAULT"
+	case CHECK:
+		// integer enums are left unchecked, string
+		// enums use the native ENUM column type.
+		return
 	default:
 		return
 	}
@@ -99,3 +93,71 @@ func (b *mysql) Index(table *Table, index *Index) string {
 	}
 	return fmt.Sprintf("CREATE %s %s ON %s (%s);", obj, index.Name, table.Name, b.columns(nil, index.Fields, true, false, false))
 }
+
+// Upsert returns an INSERT statement that updates the
+// existing row when it conflicts on a unique key.
+func (d *mysql) Upsert(t *Table, fields []*Field) string {
+	insert, update := UpsertFields(t, fields)
+
+	var params []string
+	for i := range insert {
+		params = append(params, d.Param(i))
+	}
+
+	// with nothing to update the first conflicting
+	// column is assigned to itself, leaving the row as-is.
+	version := VersionField(t)
+	if len(update) == 0 && version == nil {
+		update = fields[:1]
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "INSERT INTO %s (%s\n) VALUES (%s)\nON DUPLICATE KEY UPDATE", t.Name, d.columns(nil, insert, false, false, false), strings.Join(params, ","))
+	for i, field := range update {
+		if i == 0 {
+			buf.WriteString("\n ")
+		} else {
+			buf.WriteString("\n,")
+		}
+		fmt.Fprintf(&buf, "%s=VALUES(%s)", field.Name, field.Name)
+	}
+
+	// the version of the conflicting row is incremented,
+	// and read back when the dialect supports RETURNING.
+	if version != nil {
+		if len(update) == 0 {
+			buf.WriteString("\n ")
+		} else {
+			buf.WriteString("\n,")
+		}
+		fmt.Fprintf(&buf, "%s=%s+1", version.Name, version.Name)
+		if d.Returning() {
+			fmt.Fprintf(&buf, "\nRETURNING %s", version.Name)
+		}
+	}
+	return buf.String()
+}
+
+// FirstInsertId returns true, as the LastInsertId of a
+// multi-row insert is the key of its first row on mysql.
+func (d *mysql) FirstInsertId() bool {
+	return true
+}
+
+// MaxParams returns the maximum number of bind parameters
+// in a single statement.
+func (d *mysql) MaxParams() int {
+	return 65535
+}
+
+// ErrorFunc returns the name of the db function
+// translating the errors of the driver.
+func (d *mysql) ErrorFunc() string {
+	return "db.MySQLError"
+}
+
+// Runtime returns the name of the db package's Dialect
+// of the database, used by the statements built at run time.
+func (d *mysql) Runtime() string {
+	return "db.MySQL"
+}
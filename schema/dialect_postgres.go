@@ -11,6 +11,8 @@ type posgres struct {
 func newPosgres() Dialect {
 	d := &posgres{}
 	d.base.Dialect = d
+	// lib/pq and pgx do not support LastInsertId.
+	d.base.returning = true
 	return d
 }
 
@@ -38,6 +40,15 @@ func (d *posgres) Column(f *Field) (_ string) {
 			size = 512
 		}
 		return fmt.Sprintf("VARCHAR(%d)", size)
+	case TIMESTAMP:
+		typ := "TIMESTAMP"
+		if f.Precision != 0 {
+			typ = fmt.Sprintf("TIMESTAMP(%d)", f.Precision)
+		}
+		if f.TZ {
+			typ += " WITH TIME ZONE"
+		}
+		return typ
 	default:
 		return
 	}
@@ -51,6 +62,12 @@ func (d *posgres) Token(v int) (_ string) {
 		return
 	case PRIMARY_KEY:
 		return "PRIMARY KEY"
+	case NOT_NULL:
+		return "NOT NULL"
+	case DEFAULT:
+		return "DEFAULT"
+	case CHECK:
+		return "CHECK"
 	default:
 		return
 	}
@@ -59,3 +76,21 @@ func (d *posgres) Token(v int) (_ string) {
 func (d *posgres) Param(i int) string {
 	return fmt.Sprintf("$%d", i+1)
 }
+
+// MaxParams returns the maximum number of bind parameters
+// in a single statement.
+func (d *posgres) MaxParams() int {
+	return 65535
+}
+
+// ErrorFunc returns the name of the db function
+// translating the errors of the driver.
+func (d *posgres) ErrorFunc() string {
+	return "db.PostgresError"
+}
+
+// Runtime returns the name of the db package's Dialect
+// of the database, used by the statements built at run time.
+func (d *posgres) Runtime() string {
+	return "db.Postgres"
+}
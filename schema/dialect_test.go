@@ -0,0 +1,345 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+var enumTable = &Table{
+	Name: "tickets",
+	Fields: []*Field{
+		{Name: "f_state", Type: VARCHAR, Enum: []string{"open", "it's closed"}},
+		{Name: "f_priority", Type: INTEGER, Enum: []string{"1", "2"}},
+	},
+}
+
+var enumTests = []struct {
+	dialect int
+	want    []string
+}{
+	{SQLITE, []string{"f_state    TEXT CHECK (f_state IN ('open','it''s closed'))", "f_priority INTEGER CHECK (f_priority IN (1,2))"}},
+	{POSTGRES, []string{"CHECK (f_state IN ('open','it''s closed'))", "f_priority INTEGER CHECK (f_priority IN (1,2))"}},
+	{MYSQL, []string{"f_state    ENUM('open','it''s closed')", "f_priority INTEGER\n"}},
+}
+
+func TestTableEnum(t *testing.T) {
+	for _, test := range enumTests {
+		got := New(test.dialect).Table(enumTable)
+		for _, want := range test.want {
+			if !strings.Contains(got, want) {
+				t.Errorf("Wanted dialect %d table to contain %q, got %s", test.dialect, want, got)
+			}
+		}
+	}
+}
+
+var constraintTable = &Table{
+	Name: "accounts",
+	Fields: []*Field{
+		{Name: "f_name", Type: VARCHAR, NotNull: true, Default: "anon"},
+		{Name: "f_balance", Type: INTEGER, NotNull: true, Default: "0"},
+		{Name: "f_created", Type: TIMESTAMP, Default: "CURRENT_TIMESTAMP"},
+	},
+}
+
+func TestTableConstraints(t *testing.T) {
+	var want = []string{
+		"NOT NULL DEFAULT 'anon'",
+		"NOT NULL DEFAULT 0",
+		"DEFAULT CURRENT_TIMESTAMP",
+	}
+	for _, dialect := range Dialects {
+		got := New(dialect).Table(constraintTable)
+		for _, want := range want {
+			if !strings.Contains(got, want) {
+				t.Errorf("Wanted dialect %d table to contain %q, got %s", dialect, want, got)
+			}
+		}
+	}
+}
+
+func TestTableCompositePrimary(t *testing.T) {
+	user := &Field{Name: "f_user_id", Type: LONG, Primary: true}
+	group := &Field{Name: "f_group_id", Type: LONG, Primary: true}
+	table := &Table{
+		Name:    "memberships",
+		Fields:  []*Field{user, group, {Name: "f_role", Type: VARCHAR}},
+		Primary: []*Field{user, group},
+	}
+
+	for _, dialect := range Dialects {
+		got := New(dialect).Table(table)
+		if strings.Count(got, "PRIMARY KEY") != 1 {
+			t.Errorf("Wanted dialect %d table to declare a single PRIMARY KEY, got %s", dialect, got)
+		}
+		if !strings.Contains(got, ",PRIMARY KEY (f_user_id,f_group_id)\n);") {
+			t.Errorf("Wanted dialect %d table to end with a composite PRIMARY KEY, got %s", dialect, got)
+		}
+	}
+}
+
+func TestInsertReturning(t *testing.T) {
+	table := &Table{
+		Name: "issues",
+		Fields: []*Field{
+			{Name: "f_id", Type: LONG, Primary: true, Auto: true},
+			{Name: "f_title", Type: VARCHAR},
+		},
+	}
+
+	sqlite := New(SQLITE)
+	EnableReturning(sqlite)
+
+	var tests = []struct {
+		dialect   Dialect
+		returning bool
+	}{
+		{New(POSTGRES), true},
+		{New(SQLITE), false},
+		{New(MYSQL), false},
+		{sqlite, true},
+	}
+
+	for _, test := range tests {
+		got := test.dialect.Insert(table)
+		if strings.HasSuffix(got, "\nRETURNING f_id") != test.returning {
+			t.Errorf("Wanted RETURNING %v, got %s", test.returning, got)
+		}
+	}
+}
+
+func TestInsertBatch(t *testing.T) {
+	table := &Table{
+		Name: "issues",
+		Fields: []*Field{
+			{Name: "f_id", Type: LONG, Primary: true, Auto: true},
+			{Name: "f_title", Type: VARCHAR},
+		},
+	}
+
+	got := New(SQLITE).InsertBatch(table)
+	want := "INSERT INTO issues (\n f_title\n) VALUES "
+	if got != want {
+		t.Errorf("Wanted batch insert %q, got %q", want, got)
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	id := &Field{Name: "f_id", Type: LONG, Primary: true, Auto: true}
+	login := &Field{Name: "f_login", Type: VARCHAR}
+	email := &Field{Name: "f_email", Type: VARCHAR}
+	table := &Table{
+		Name:    "users",
+		Fields:  []*Field{id, login, email},
+		Primary: []*Field{id},
+	}
+
+	var tests = []struct {
+		dialect Dialect
+		fields  []*Field
+		want    string
+	}{
+		{New(SQLITE), []*Field{login}, "INSERT INTO users (\n f_login\n,f_email\n) VALUES (?,?)\nON CONFLICT (f_login) DO UPDATE SET\n f_email=excluded.f_email"},
+		{New(POSTGRES), []*Field{id}, "INSERT INTO users (\n f_id\n,f_login\n,f_email\n) VALUES ($1,$2,$3)\nON CONFLICT (f_id) DO UPDATE SET\n f_login=excluded.f_login\n,f_email=excluded.f_email"},
+		{New(MYSQL), []*Field{login}, "INSERT INTO users (\n f_login\n,f_email\n) VALUES (?,?)\nON DUPLICATE KEY UPDATE\n f_email=VALUES(f_email)"},
+		{New(SQLITE), []*Field{login, email}, "INSERT INTO users (\n f_login\n,f_email\n) VALUES (?,?)\nON CONFLICT (f_login,f_email) DO NOTHING"},
+		{New(MYSQL), []*Field{login, email}, "INSERT INTO users (\n f_login\n,f_email\n) VALUES (?,?)\nON DUPLICATE KEY UPDATE\n f_login=VALUES(f_login)"},
+	}
+
+	for _, test := range tests {
+		got := test.dialect.Upsert(table, test.fields)
+		if got != test.want {
+			t.Errorf("Wanted upsert %q, got %q", test.want, got)
+		}
+	}
+}
+
+func TestUpsertKeys(t *testing.T) {
+	id := &Field{Name: "f_id", Type: LONG, Primary: true, Auto: true}
+	login := &Field{Name: "f_login", Type: VARCHAR}
+	version := &Field{Name: "f_version", Type: LONG, Version: true}
+	deleted := &Field{Name: "f_deleted", Type: TIMESTAMP, SoftDelete: true}
+	table := &Table{
+		Name:    "users",
+		Fields:  []*Field{id, login, deleted},
+		Primary: []*Field{id},
+		Index:   []*Index{{Name: "user_login", Unique: true, Fields: []*Field{login}}},
+	}
+
+	// the auto-increment primary key has no upsert.
+	keys := UpsertKeys(table)
+	if len(keys) != 1 || keys[0][0] != login {
+		t.Errorf("Wanted upsert by login only, got %v", keys)
+	}
+
+	// the deleted row is revived.
+	want := "INSERT INTO users (\n f_login\n,f_deleted\n) VALUES (?,?)\nON CONFLICT (f_login) DO UPDATE SET\n f_deleted=excluded.f_deleted"
+	if got := New(SQLITE).Upsert(table, []*Field{login}); got != want {
+		t.Errorf("Wanted upsert %q, got %q", want, got)
+	}
+
+	// a versioned table keeps its upserts, which increment
+	// the version of the conflicting row and read it back.
+	table.Fields = append(table.Fields, version)
+	keys = UpsertKeys(table)
+	if len(keys) != 1 || keys[0][0] != login {
+		t.Errorf("Wanted upsert of a versioned table by login, got %v", keys)
+	}
+	var tests = []struct {
+		dialect Dialect
+		want    string
+	}{
+		{New(SQLITE), "INSERT INTO users (\n f_login\n,f_deleted\n,f_version\n) VALUES (?,?,?)\nON CONFLICT (f_login) DO UPDATE SET\n f_deleted=excluded.f_deleted\n,f_version=users.f_version+1"},
+		{New(POSTGRES), "INSERT INTO users (\n f_login\n,f_deleted\n,f_version\n) VALUES ($1,$2,$3)\nON CONFLICT (f_login) DO UPDATE SET\n f_deleted=excluded.f_deleted\n,f_version=users.f_version+1\nRETURNING f_version"},
+		{New(MYSQL), "INSERT INTO users (\n f_login\n,f_deleted\n,f_version\n) VALUES (?,?,?)\nON DUPLICATE KEY UPDATE\n f_deleted=VALUES(f_deleted)\n,f_version=f_version+1"},
+	}
+	for _, test := range tests {
+		if got := test.dialect.Upsert(table, []*Field{login}); got != test.want {
+			t.Errorf("Wanted versioned upsert %q, got %q", test.want, got)
+		}
+	}
+
+	// with nothing else to update the version is still
+	// incremented.
+	table.Fields = []*Field{id, login, version}
+	want = "INSERT INTO users (\n f_login\n,f_version\n) VALUES (?,?)\nON CONFLICT (f_login) DO UPDATE SET\n f_version=users.f_version+1"
+	if got := New(SQLITE).Upsert(table, []*Field{login}); got != want {
+		t.Errorf("Wanted versioned upsert %q, got %q", want, got)
+	}
+	want = "SELECT f_version\nFROM users \nWHERE f_login=?"
+	if got := New(SQLITE).SelectVersion(table, []*Field{login}); got != want {
+		t.Errorf("Wanted version select %q, got %q", want, got)
+	}
+}
+
+func TestUpdateColumns(t *testing.T) {
+	id := &Field{Name: "f_id", Type: LONG, Primary: true, Auto: true}
+	login := &Field{Name: "f_login", Type: VARCHAR}
+	email := &Field{Name: "f_email", Type: VARCHAR}
+	table := &Table{
+		Name:    "users",
+		Fields:  []*Field{id, login, email},
+		Primary: []*Field{id},
+	}
+
+	want := "UPDATE users SET \n f_login=$1\n,f_email=$2 \nWHERE f_id=$3"
+	if got := New(POSTGRES).UpdateColumns(table, SetFields(table), table.Primary); got != want {
+		t.Errorf("Wanted update %q, got %q", want, got)
+	}
+	want = "UPDATE users SET \n f_id=?\n,f_login=?\n,f_email=? \nWHERE f_login=?"
+	if got := New(SQLITE).Update(table, []*Field{login}); got != want {
+		t.Errorf("Wanted update %q, got %q", want, got)
+	}
+
+	version := &Field{Name: "f_version", Type: INTEGER, Version: true}
+	table.Fields = append(table.Fields, version)
+	want = "UPDATE users SET \n f_login=$1\n,f_email=$2\n,f_version=f_version+1 \nWHERE f_id=$3\nAND f_version=$4"
+	if got := New(POSTGRES).UpdateColumns(table, SetFields(table), table.Primary); got != want {
+		t.Errorf("Wanted versioned update %q, got %q", want, got)
+	}
+}
+
+func TestSelectAfter(t *testing.T) {
+	id := &Field{Name: "f_id", Type: LONG, Primary: true}
+	seq := &Field{Name: "f_seq", Type: INTEGER, Primary: true}
+	assignee := &Field{Name: "f_assignee", Type: VARCHAR}
+	table := &Table{
+		Name:    "hooks",
+		Fields:  []*Field{id, assignee},
+		Primary: []*Field{id},
+	}
+	composite := &Table{
+		Name:    "hooks",
+		Fields:  []*Field{id, seq},
+		Primary: []*Field{id, seq},
+	}
+
+	var tests = []struct {
+		dialect Dialect
+		table   *Table
+		fields  []*Field
+		after   bool
+		want    string
+	}{
+		{New(SQLITE), table, nil, false, "\nFROM hooks \nORDER BY f_id\nLIMIT ?"},
+		{New(SQLITE), table, nil, true, "\nFROM hooks \nWHERE f_id > ?\nORDER BY f_id\nLIMIT ?"},
+		{New(POSTGRES), table, []*Field{assignee}, true, "\nFROM hooks \nWHERE f_assignee=$1\nAND f_id > $2\nORDER BY f_id\nLIMIT $3"},
+		{New(POSTGRES), composite, nil, true, "\nFROM hooks \nWHERE (f_id,f_seq) > ($1,$2)\nORDER BY f_id,f_seq\nLIMIT $3"},
+	}
+
+	for _, test := range tests {
+		got := test.dialect.SelectAfter(test.table, test.fields, test.after)
+		if !strings.HasSuffix(got, test.want) {
+			t.Errorf("Wanted select ending %q, got %q", test.want, got)
+		}
+	}
+}
+
+func TestSelectOrder(t *testing.T) {
+	id := &Field{Name: "f_id", Type: LONG, Primary: true}
+	created := &Field{Name: "f_created", Type: TIMESTAMP, Order: "DESC"}
+	assignee := &Field{Name: "f_assignee", Type: VARCHAR}
+	table := &Table{
+		Name:    "issues",
+		Fields:  []*Field{id, created, assignee},
+		Primary: []*Field{id},
+	}
+
+	var tests = []struct {
+		got  string
+		want string
+	}{
+		{New(SQLITE).Select(table, nil), "\nFROM issues \nORDER BY f_created DESC,f_id"},
+		{New(POSTGRES).SelectRange(table, []*Field{assignee}), "\nWHERE f_assignee=$1\nORDER BY f_created DESC,f_id\nLIMIT $2 OFFSET $3"},
+		{New(SQLITE).SelectSorted(table, nil, false), "\nFROM issues \nORDER BY %s,f_id"},
+		{New(POSTGRES).SelectSorted(table, []*Field{assignee}, true), "\nWHERE f_assignee=$1\nORDER BY %s,f_id\nLIMIT $2 OFFSET $3"},
+		{New(MYSQL).SelectQuery(table), "f_assignee\nFROM issues"},
+	}
+
+	for _, test := range tests {
+		if !strings.HasSuffix(test.got, test.want) {
+			t.Errorf("Wanted select ending %q, got %q", test.want, test.got)
+		}
+	}
+}
+
+func TestSoftDelete(t *testing.T) {
+	id := &Field{Name: "f_id", Type: LONG, Primary: true, Auto: true}
+	deleted := &Field{Name: "f_deleted", Type: TIMESTAMP, SoftDelete: true}
+	table := &Table{
+		Name:    "notes",
+		Fields:  []*Field{id, deleted},
+		Primary: []*Field{id},
+	}
+
+	d := New(SQLITE)
+	if got := d.Select(table, table.Primary); !strings.Contains(got, "AND f_deleted IS NULL") {
+		t.Errorf("Wanted select of live rows, got %q", got)
+	}
+	if got := d.Delete(table, table.Primary); !strings.HasPrefix(got, "UPDATE notes SET f_deleted=CURRENT_TIMESTAMP") {
+		t.Errorf("Wanted soft delete, got %q", got)
+	}
+	if got := d.Delete(WithDeleted(table), table.Primary); !strings.HasPrefix(got, "DELETE FROM notes") {
+		t.Errorf("Wanted hard delete, got %q", got)
+	}
+	if !deleted.SoftDelete {
+		t.Errorf("Wanted WithDeleted to leave the table unchanged")
+	}
+}
+
+func TestUpsertAutoTime(t *testing.T) {
+	id := &Field{Name: "f_id", Type: LONG, Primary: true, Auto: true}
+	login := &Field{Name: "f_login", Type: VARCHAR}
+	created := &Field{Name: "f_created", Type: TIMESTAMP, AutoCreateTime: true}
+	updated := &Field{Name: "f_updated", Type: TIMESTAMP, AutoUpdateTime: true}
+	table := &Table{
+		Name:    "users",
+		Fields:  []*Field{id, login, created, updated},
+		Primary: []*Field{id},
+	}
+
+	want := "INSERT INTO users (\n f_login\n,f_created\n,f_updated\n) VALUES (?,?,?)\nON CONFLICT (f_login) DO UPDATE SET\n f_updated=excluded.f_updated"
+	if got := New(SQLITE).Upsert(table, []*Field{login}); got != want {
+		t.Errorf("Wanted upsert %q, got %q", want, got)
+	}
+}
@@ -31,7 +31,13 @@ func Load(tree *parse.Node) *Table {
 		// Lookup the SQL column type
 		// TODO: move this to a function
 		t, ok := parse.Types[node.Type]
-		if ok {
+		if node.Kind == parse.Valuer {
+			tt, ok := ValuerTypes[node.Type]
+			if !ok {
+				tt = BLOB
+			}
+			field.Type = tt
+		} else if ok {
 			tt, ok := types[t]
 			if !ok {
 				tt = BLOB
@@ -45,6 +51,11 @@ func Load(tree *parse.Node) *Table {
 		path := node.Path()
 		var parts []string
 		for _, part := range path {
+			// embedded structs are flattened without
+			// a prefix, like Go's promoted fields.
+			if part.Inline {
+				continue
+			}
 			if part.Tags != nil && part.Tags.Name != "" {
 				parts = append(parts, part.Tags.Name)
 				continue
@@ -74,7 +85,7 @@ func Load(tree *parse.Node) *Table {
 
 			// default ID and int64 to primary key
 			// with auto-increment
-			if node.Name == "ID" && node.Kind == parse.Int64 {
+			if node.Name == "ID" && node.Kind == parse.Int64 && !node.Pointer && node.Named == "" {
 				node.Tags.Primary = true
 				node.Tags.Auto = true
 			}
@@ -82,6 +93,57 @@ func Load(tree *parse.Node) *Table {
 			field.Auto = node.Tags.Auto
 			field.Primary = node.Tags.Primary
 			field.Size = node.Tags.Size
+			field.Precision = node.Tags.Precision
+			field.TZ = node.Tags.TZ
+			field.NotNull = node.Tags.NotNull
+			field.Default = node.Tags.Default
+
+			if node.Tags.Version {
+				if (field.Type == INTEGER || field.Type == LONG) && !node.Pointer {
+					field.Version = true
+				} else {
+					log.Printf("ignore version tag of non-integer field %s\n", node.Name)
+				}
+			}
+
+			// a deleted-at timestamp must be nullable, as
+			// NULL marks the rows that are not deleted.
+			if node.Tags.SoftDelete {
+				if node.Parent != nil && node.Parent.Kind == parse.Ptr {
+					log.Printf("ignore softdelete tag of field %s in a pointer struct\n", node.Name)
+				} else if (field.Type == TIMESTAMP && node.Pointer) || (field.Type == BOOLEAN && !node.Pointer) {
+					field.SoftDelete = true
+				} else {
+					log.Printf("ignore softdelete tag of field %s, which must be a *time.Time or bool\n", node.Name)
+				}
+			}
+
+			// the timestamps are set by the generated code
+			// rather than the database, from db.Now.
+			if node.Tags.AutoCreateTime || node.Tags.AutoUpdateTime {
+				if node.Parent != nil && node.Parent.Kind == parse.Ptr {
+					log.Printf("ignore auto time tag of field %s in a pointer struct\n", node.Name)
+				} else if field.Type == TIMESTAMP && !node.Pointer {
+					field.AutoCreateTime = node.Tags.AutoCreateTime
+					field.AutoUpdateTime = node.Tags.AutoUpdateTime
+				} else {
+					log.Printf("ignore auto time tag of field %s, which must be a time.Time\n", node.Name)
+				}
+			}
+
+			switch order := strings.ToUpper(node.Tags.Order); order {
+			case "":
+			case "ASC", "DESC":
+				field.Order = order
+			default:
+				log.Printf("ignore invalid order %s of field %s\n", node.Tags.Order, node.Name)
+			}
+
+			for _, value := range strings.Split(node.Tags.Enum, ",") {
+				if value = strings.TrimSpace(value); value != "" {
+					field.Enum = append(field.Enum, value)
+				}
+			}
 
 			if node.Tags.Primary {
 				table.Primary = append(table.Primary, field)
@@ -235,6 +297,23 @@ var types = map[uint8]int{
 	parse.String:     VARCHAR,
 	parse.Map:        BLOB,
 	parse.Slice:      BLOB,
+	parse.Time:       TIMESTAMP,
+}
+
+// ValuerTypes maps types implementing sql.Scanner and
+// driver.Valuer, as written in the source code, to the
+// SQL type of their column. A type tag on the field takes
+// precedence over this mapping.
+var ValuerTypes = map[string]int{
+	"uuid.UUID":       VARCHAR,
+	"decimal.Decimal": VARCHAR,
+}
+
+// SqlType returns the SQL type for the type name used
+// in tags, such as varchar or long.
+func SqlType(name string) (int, bool) {
+	t, ok := sqlTypes[name]
+	return t, ok
 }
 
 var sqlTypes = map[string]int{
@@ -251,4 +330,6 @@ var sqlTypes = map[string]int{
 	"float":    FLOAT,
 	"MEDIUMTEXT": MEDIUMTEXT,
 	"LONGTEXT": LONGTEXT,
+	"timestamp": TIMESTAMP,
+	"datetime": TIMESTAMP,
 }
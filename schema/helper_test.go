@@ -0,0 +1,57 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/linchunquan/sqlgen/parse"
+)
+
+func TestLoadInline(t *testing.T) {
+	base := &parse.Node{Name: "BaseModel", Kind: parse.Struct, Inline: true, Tags: &parse.Tag{}}
+	base.Nodes = []*parse.Node{
+		{Name: "ID", Type: "int64", Kind: parse.Int64, Tags: &parse.Tag{}, Parent: base},
+		{Name: "Created", Type: "int64", Kind: parse.Int64, Tags: &parse.Tag{}, Parent: base},
+	}
+	meta := &parse.Node{Name: "Meta", Kind: parse.Ptr, Tags: &parse.Tag{}}
+	meta.Nodes = []*parse.Node{
+		{Name: "Source", Type: "string", Kind: parse.String, Tags: &parse.Tag{}, Parent: meta},
+	}
+	tree := &parse.Node{Name: "Post", Type: "Post", Nodes: []*parse.Node{base, meta}}
+	base.Parent, meta.Parent = tree, tree
+
+	table := Load(tree)
+
+	var want = []string{"f_id", "f_created", "f_meta_source"}
+	if len(table.Fields) != len(want) {
+		t.Fatalf("Wanted %d fields, got %d", len(want), len(table.Fields))
+	}
+	for i, field := range table.Fields {
+		if field.Name != want[i] {
+			t.Errorf("Wanted field name %s, got %s", want[i], field.Name)
+		}
+	}
+	if len(table.Primary) != 1 || table.Primary[0].Name != "f_id" {
+		t.Errorf("Wanted promoted ID field as primary key")
+	}
+}
+
+func TestLoadValuer(t *testing.T) {
+	tree := &parse.Node{Name: "Order", Type: "Order"}
+	for _, node := range []*parse.Node{
+		{Name: "Ref", Type: "uuid.UUID", Kind: parse.Valuer, Tags: &parse.Tag{}},
+		{Name: "Price", Type: "Money", Kind: parse.Valuer, Tags: &parse.Tag{}},
+		{Name: "Alt", Type: "uuid.UUID", Kind: parse.Valuer, Tags: &parse.Tag{Type: "blob"}},
+	} {
+		node.Parent = tree
+		tree.Nodes = append(tree.Nodes, node)
+	}
+
+	table := Load(tree)
+
+	var want = []int{VARCHAR, BLOB, BLOB}
+	for i, field := range table.Fields {
+		if field.Type != want[i] {
+			t.Errorf("Wanted field %s of type %d, got %d", field.Name, want[i], field.Type)
+		}
+	}
+}
@@ -16,12 +16,16 @@ const (
 	DOUBLE
 	MEDIUMTEXT
 	LONGTEXT
+	TIMESTAMP
 )
 
 // List of vendor-specific keywords
 const (
 	AUTO_INCREMENT = iota
 	PRIMARY_KEY
+	CHECK
+	NOT_NULL
+	DEFAULT
 )
 
 type Table struct {
@@ -39,12 +43,22 @@ type Field struct {
 	Primary bool
 	Auto    bool
 	Size    int
+	Precision int
+	TZ      bool
+	Enum    []string
+	NotNull bool
+	Default string
+	Order   string
+	Version bool
+	SoftDelete bool
+	AutoCreateTime bool
+	AutoUpdateTime bool
 	Operator string
 	ValueAsFirstArg bool
 }
 
 func(f*Field)Clone()*Field{
-	return &Field{Node:f.Node, Name:f.Name, Type:f.Type, Primary:f.Primary, Auto:f.Auto, Size:f.Size, Operator:f.Operator, ValueAsFirstArg:f.ValueAsFirstArg}
+	return &Field{Node:f.Node, Name:f.Name, Type:f.Type, Primary:f.Primary, Auto:f.Auto, Size:f.Size, Precision:f.Precision, TZ:f.TZ, Enum:f.Enum, NotNull:f.NotNull, Default:f.Default, Order:f.Order, Version:f.Version, SoftDelete:f.SoftDelete, AutoCreateTime:f.AutoCreateTime, AutoUpdateTime:f.AutoUpdateTime, Operator:f.Operator, ValueAsFirstArg:f.ValueAsFirstArg}
 }
 
 type Index struct {
@@ -60,4 +74,138 @@ type Foreign struct{
 	ToTable     string
 	ToColumns   []string
 	Many        bool
-}
\ No newline at end of file
+}
+
+// UpsertKeys returns the keys a table has upserts for,
+// which are its unique indexes and its primary key unless
+// that is auto-incremented, as the key of a new row is then
+// only known once it is inserted.
+func UpsertKeys(t *Table) [][]*Field {
+	var keys [][]*Field
+	if len(t.Primary) != 0 && !hasAuto(t.Primary) {
+		keys = append(keys, t.Primary)
+	}
+	for _, ix := range t.Index {
+		if ix.Unique {
+			keys = append(keys, ix.Fields)
+		}
+	}
+	return keys
+}
+
+func hasAuto(fields []*Field) bool {
+	for _, field := range fields {
+		if field.Auto {
+			return true
+		}
+	}
+	return false
+}
+
+// UpsertFields returns the fields inserted and the fields
+// updated by an upsert that conflicts on the given fields.
+// Auto fields are only inserted when they are part of the
+// conflict, and primary keys, versions and creation times
+// are never updated, the version being incremented by the
+// statement instead. The soft delete field is updated like
+// any other, so that upserting a row revives the deleted row
+// it conflicts with.
+func UpsertFields(t *Table, conflict []*Field) (insert, update []*Field) {
+	names := map[string]bool{}
+	for _, field := range conflict {
+		names[field.Name] = true
+	}
+	for _, field := range t.Fields {
+		if field.Auto && !names[field.Name] {
+			continue
+		}
+		insert = append(insert, field)
+		if !names[field.Name] && !field.Primary && !field.Version && !field.AutoCreateTime {
+			update = append(update, field)
+		}
+	}
+	return
+}
+
+// SetFields returns the fields of a table that are neither
+// primary keys nor auto-increment, which are the columns an
+// update may change without touching the row's identity.
+func SetFields(t *Table) []*Field {
+	var fields []*Field
+	for _, field := range t.Fields {
+		if !field.Primary && !field.Auto {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// VersionField returns the version field of a table used
+// for optimistic locking, or nil if it has none.
+func VersionField(t *Table) *Field {
+	for _, field := range t.Fields {
+		if field.Version {
+			return field
+		}
+	}
+	return nil
+}
+
+// AutoTimeFields returns the fields of a table set to the
+// current time on insert, and those set on insert and update.
+func AutoTimeFields(t *Table) (create, update []*Field) {
+	for _, field := range t.Fields {
+		if field.AutoCreateTime {
+			create = append(create, field)
+		}
+		if field.AutoUpdateTime {
+			update = append(update, field)
+		}
+	}
+	return
+}
+
+// SoftDeleteField returns the field marking the deleted
+// rows of a table, or nil if rows are deleted physically.
+func SoftDeleteField(t *Table) *Field {
+	for _, field := range t.Fields {
+		if field.SoftDelete {
+			return field
+		}
+	}
+	return nil
+}
+
+// WithDeleted returns a copy of a table without its soft
+// delete field, whose statements select the rows marked as
+// deleted along with the others, and physically delete rows.
+func WithDeleted(t *Table) *Table {
+	c := *t
+	c.Fields = nil
+	for _, field := range t.Fields {
+		if field.SoftDelete {
+			field = field.Clone()
+			field.SoftDelete = false
+		}
+		c.Fields = append(c.Fields, field)
+	}
+	return &c
+}
+
+// OrderFields returns the fields a table is sorted by
+// default, which are the fields with an order tag followed
+// by the primary key, so that the order is deterministic.
+func OrderFields(t *Table) []*Field {
+	var fields []*Field
+	for _, field := range t.Fields {
+		if field.Order != "" {
+			fields = append(fields, field)
+		}
+	}
+	for _, field := range t.Primary {
+		if field.Order == "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,9 @@
+package embed
+
+import "time"
+
+// Base is embedded by the other types of the package.
+type Base struct {
+	ID      int64     `sql:"pk: true, auto: true"`
+	Created time.Time `sql:"autoCreateTime: true"`
+}
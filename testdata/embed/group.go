@@ -0,0 +1,9 @@
+package embed
+
+// Group embeds the same type as User, which is still not
+// a table of its own.
+type Group struct {
+	*Base
+
+	Name string `sql:"unique: group_name"`
+}
@@ -0,0 +1,15 @@
+package other
+
+// Audit is embedded from another package.
+type Audit struct {
+	Editor string
+}
+
+// Status is a named basic type.
+type Status int
+
+// Address is a nested struct.
+type Address struct {
+	City string
+	Zip  string
+}
@@ -0,0 +1,14 @@
+package embed
+
+import "github.com/linchunquan/sqlgen/testdata/embed/other"
+
+// User embeds a type from another file and refers to
+// types from another package.
+type User struct {
+	Base
+	other.Audit
+
+	Login   string `sql:"unique: user_login"`
+	Status  other.Status
+	Address other.Address
+}
@@ -0,0 +1,3 @@
+// Package gen holds the code generated by the tests, which
+// is type checked in place of an out.go file.
+package gen
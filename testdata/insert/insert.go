@@ -0,0 +1,16 @@
+package insert
+
+// Note has its auto-increment key after another column, so
+// the insert must not assume the key is the first column.
+type Note struct {
+	Title string `sql:"index: note_title"`
+	ID    int64  `sql:"pk: true, auto: true"`
+	Body  string
+}
+
+// Tag has a text primary key and no auto-increment column,
+// so every column is inserted and no key is read back.
+type Tag struct {
+	Name  string `sql:"pk: true"`
+	Color string
+}
@@ -0,0 +1,11 @@
+package pointertime
+
+import "time"
+
+// Event has only pointer time fields, which are nullable
+// columns but still need the time package in the output.
+type Event struct {
+	ID      int64      `sql:"pk: true, auto: true"`
+	Started *time.Time `sql:"index: event_started"`
+	Deleted *time.Time `sql:"softdelete: true"`
+}
@@ -0,0 +1,44 @@
+package promote
+
+type A struct {
+	ID   int64
+	Name string
+}
+
+type B struct {
+	ID    int64
+	Email string
+}
+
+type C struct {
+	A
+}
+
+type D struct {
+	ID int64
+}
+
+type E struct {
+	B
+}
+
+// X embeds A and B, which both declare ID at the same
+// depth, so neither is promoted.
+type X struct {
+	A
+	B
+	Login string `sql:"pk: true"`
+}
+
+// Y promotes the ID of D over the deeper ID of C.A.
+type Y struct {
+	C
+	D
+}
+
+// Z embeds the ID of C.A and of E.B at the same depth, so
+// neither is promoted.
+type Z struct {
+	C
+	E
+}
@@ -0,0 +1,10 @@
+package skip
+
+// Job has fields of types that cannot be stored, which
+// are skipped.
+type Job struct {
+	ID   int64 `sql:"pk: true"`
+	Done chan bool
+	Run  func() error
+	Name string
+}
@@ -0,0 +1,14 @@
+package stale
+
+// User has lost the Name field that user_sql.go, generated
+// before it was removed, still refers to.
+type User struct {
+	ID    int64  `sql:"pk: true, auto: true"`
+	Login string `sql:"unique: user_login"`
+}
+
+// Account refers to a type that is not declared.
+type Account struct {
+	ID    int64 `sql:"pk: true, auto: true"`
+	Owner Owner
+}
@@ -0,0 +1,5 @@
+package stale
+
+func userName(u *User) string {
+	return u.Name
+}
@@ -0,0 +1,38 @@
+package valuer
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+// Code implements sql.Scanner and driver.Valuer with
+// pointer receivers, so only a *Code is a driver.Valuer.
+type Code string
+
+func (c *Code) Scan(src interface{}) error {
+	*c = Code(fmt.Sprint(src))
+	return nil
+}
+
+func (c *Code) Value() (driver.Value, error) {
+	return string(*c), nil
+}
+
+// Tag implements driver.Valuer with a value receiver.
+type Tag string
+
+func (t *Tag) Scan(src interface{}) error {
+	*t = Tag(fmt.Sprint(src))
+	return nil
+}
+
+func (t Tag) Value() (driver.Value, error) {
+	return string(t), nil
+}
+
+type Item struct {
+	ID      int64 `sql:"pk: true"`
+	Code    Code
+	CodePtr *Code
+	Tag     Tag
+}
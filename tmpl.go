@@ -76,16 +76,16 @@ func slice%s(v *%s) []interface{} {
 `
 
 const sGenericSelectRow = `
-func genericSelect%s(db db.SimpleDB, query string, args ...interface{}) (*%s, error) {
-	row := db.QueryRow(query, args...)
+func genericSelect%s{{.Ctx}}({{.CtxParam}}db {{.DB}}, query string, args ...interface{}) (*%s, error) {
+	row := db.QueryRow{{.Context}}({{.CtxArg}}query, args...)
 	return scan%s(row)
 }
 `
 
 // function template to select multiple rows.
 const sGenericSelectRows = `
-func genericSelect%s(db db.SimpleDB, query string, args ...interface{}) ([]*%s, error) {
-	rows, err := db.Query(query, args...)
+func genericSelect%s{{.Ctx}}({{.CtxParam}}db {{.DB}}, query string, args ...interface{}) ([]*%s, error) {
+	rows, err := db.Query{{.Context}}({{.CtxArg}}query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -96,9 +96,9 @@ func genericSelect%s(db db.SimpleDB, query string, args ...interface{}) ([]*%s,
 
 // function template to insert a single row.
 const sGenericInsert = `
-func genericInsert%s(db db.SimpleDB, query string, v *%s) error {
+func genericInsert%s{{.Ctx}}({{.CtxParam}}db {{.DB}}, query string, v *%s) error {
 
-	res, err := db.Exec(query, slice%s(v)[1:]...)
+	res, err := db.Exec{{.Context}}({{.CtxArg}}query, slice%s(v)[1:]...)
 	if err != nil {
 		return err
 	}
@@ -110,123 +110,758 @@ func genericInsert%s(db db.SimpleDB, query string, v *%s) error {
 
 // function template to update a single row.
 const sGenericUpdate = `
-func genericUpdate%s(db db.SimpleDB, query string, v *%s) error {
+func genericUpdate%s{{.Ctx}}({{.CtxParam}}db {{.DB}}, query string, v *%s) error {
 
 	args := slice%s(v)[1:]
 	args = append(args, v.ID)
-	_, err := db.Exec(query, args...)
+	_, err := db.Exec{{.Context}}({{.CtxArg}}query, args...)
 	return err 
 }
 `
 
+// function template to insert a single row, passing
+// every column except the auto-increment column and
+// assigning the generated key back to the struct.
 const sInsert = `
-func Insert%s(db db.SimpleDB,  v *%s) error {
+func Insert%s{{.Ctx}}({{.CtxParam}}db {{.DB}},  v *%s) (err error) {
+%s%s	args := slice%s(v)
+	res, err := db.Exec{{.Context}}({{.CtxArg}}%s, %s)
+	if err != nil {
+		return err
+	}
 
-	res, err := db.Exec(%s, slice%s(v)[1:]...)
+	id, err := res.LastInsertId()
 	if err != nil {
 		return err
 	}
+	v.%s = %s
+%s	return nil
+}
+`
+// function template to insert a single row, reading
+// the generated keys back with INSERT ... RETURNING.
+const sInsertReturning = `
+func Insert%s{{.Ctx}}({{.CtxParam}}db {{.DB}},  v *%s) (err error) {
+%s%s	args := slice%s(v)
+	row := db.QueryRow{{.Context}}({{.CtxArg}}%s, %s)
+	if err := row.Scan(%s); err != nil {
+		return err
+	}
+%s	return nil
+}
+`
 
-	v.ID, err = res.LastInsertId()
-	return err
+// function template to insert a single row into a
+// table without an auto-increment column.
+const sInsertNoAuto = `
+func Insert%s{{.Ctx}}({{.CtxParam}}db {{.DB}},  v *%s) (err error) {
+%s%s	if _, err := db.Exec{{.Context}}({{.CtxArg}}%s, slice%s(v)...); err != nil {
+		return err
+	}
+%s	return nil
 }
 `
+
+// function template returning the multi-row INSERT
+// statement for n rows.
+const sInsertBatchQuery = `
+func insert%sBatch(n int) string {
+	return %s + db.BatchValues(n, %d, %s)%s
+}
+`
+
+// function template to insert rows in chunks that stay
+// within the dialect's limit on bind parameters. A batch of
+// more than one chunk is inserted in a transaction, unless db
+// already is one, so that it is never partially applied.
+const sInsertBatch = `
+func Insert%s{{.Ctx}}({{.CtxParam}}db {{.DB}}, vv []*%s) (err error) {
+%s	if len(vv) <= %d {
+		return insert%sChunks{{.Ctx}}({{.CtxArg}}db, vv)
+	}
+	return insert%sAtomic{{.Ctx}}({{.CtxArg}}db, vv)
+}
+
+func insert%sAtomic{{.Ctx}}({{.CtxParam}}conn {{.DB}}, vv []*%s) error {
+	return db.Atomic{{.Context}}({{.CtxArg}}conn, func(conn {{.DB}}) error {
+		return insert%sChunks{{.Ctx}}({{.CtxArg}}conn, vv)
+	})
+}
+
+func insert%sChunks{{.Ctx}}({{.CtxParam}}db {{.DB}}, vv []*%s) error {
+	for len(vv) != 0 {
+		n := len(vv)
+		if n > %d {
+			n = %d
+		}
+		args := make([]interface{}, 0, n*%d)
+		for _, v := range vv[:n] {
+%s			a := slice%s(v)
+			args = append(args, %s)
+		}
+		%s
+%s		vv = vv[n:]
+	}
+	return nil
+}
+`
+
+// statement executing a chunk of a multi-row insert.
+const sInsertBatchExec = `if _, err := db.Exec{{.Context}}({{.CtxArg}}insert%sBatch(n), args...); err != nil {
+			return err
+		}`
+
+// statements executing a chunk of a multi-row insert,
+// assigning the consecutive keys that follow from the
+// LastInsertId of the chunk, which is the key of its first row
+// or, when adjusted, of its last row.
+const sInsertBatchLastId = `res, err := db.Exec{{.Context}}({{.CtxArg}}insert%sBatch(n), args...)
+		if err != nil {
+			return err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+%s		for _, v := range vv[:n] {
+			v.%s = %s
+			id++
+		}`
+
+// statements executing a chunk of a multi-row insert,
+// reading the generated keys back with RETURNING. The keys
+// are assigned to the rows in the order they are returned,
+// which is the order of the VALUES in practice, though not
+// one the databases guarantee.
+const sInsertBatchReturning = `rows, err := db.Query{{.Context}}({{.CtxArg}}insert%sBatch(n), args...)
+		if err != nil {
+			return err
+		}
+		for _, v := range vv[:n] {
+			if !rows.Next() {
+				break
+			}
+			if err := rows.Scan(%s); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return err
+		}`
+
 const sDelete = `
-func Delete%s%s(db db.SimpleDB, %s) error {
-	args := []interface{}{%s}
-	_, err := db.Exec(%s, args...)
+func Delete%s%s{{.Ctx}}({{.CtxParam}}db {{.DB}}, %s) (err error) {
+%s	args := []interface{}{%s}
+	_, err = db.Exec{{.Context}}({{.CtxArg}}%s, args...)
+	return err
+}
+`
+
+// function template to physically delete the rows of a
+// table with a soft delete field.
+const sHardDelete = `
+func HardDelete%s%s{{.Ctx}}({{.CtxParam}}db {{.DB}}, %s) (err error) {
+%s	args := []interface{}{%s}
+	_, err = db.Exec{{.Context}}({{.CtxArg}}%s, args...)
 	return err
 }
 `
 
 const sUpdate = `
-func Update%s%s(db db.SimpleDB, v *%s) error {
-	args := slice%s(v)
+func Update%s%s{{.Ctx}}({{.CtxParam}}db {{.DB}}, v *%s) (err error) {
+%s%s	args := slice%s(v)
     args = append(args,%s)
-	_, err := db.Exec(%s, args...)
+	_, err = db.Exec{{.Context}}({{.CtxArg}}%s, args...)
 	return err
 }
 `
 
+// function template to update a row, passing only the
+// values of the columns that are set.
+const sUpdateSet = `
+func Update%s%s{{.Ctx}}({{.CtxParam}}db {{.DB}}, v *%s) (err error) {
+%s%s	a := slice%s(v)
+	_, err = db.Exec{{.Context}}({{.CtxArg}}%s, %s)
+	return err
+}
+`
+
+// function template to update a row with a version
+// column, which fails with an ErrStaleObject if the row was
+// changed since it was read, and increments the version of v
+// on success.
+const sUpdateVersion = `
+func Update%s%s{{.Ctx}}({{.CtxParam}}db {{.DB}}, v *%s) (err error) {
+%s%s	a := slice%s(v)
+	res, err := db.Exec{{.Context}}({{.CtxArg}}%s, %s)
+	if err != nil {
+		return err
+	}
+	if err := %sCheckVersion(res); err != nil {
+		return err
+	}
+	v.%s++
+	return nil
+}
+`
+
+// function template to update the listed columns of a
+// row by its primary key, which rejects the key columns
+// unless -updateKeys is true.
+const sUpdateFields = `
+func Update%sFields{{.Ctx}}({{.CtxParam}}db {{.DB}}, v *%s, cols ...%sColumn) (err error) {
+%s	if len(cols) == 0 {
+		return nil
+	}
+%s	query, args, err := %sUpdateFieldsQuery(v, cols)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec{{.Context}}({{.CtxArg}}query, args...)
+	return err
+}
+`
+
+// function template to update the listed columns of a
+// row with a version column, as in sUpdateVersion.
+const sUpdateFieldsVersion = `
+func Update%sFields{{.Ctx}}({{.CtxParam}}db {{.DB}}, v *%s, cols ...%sColumn) (err error) {
+%s	if len(cols) == 0 {
+		return nil
+	}
+%s	query, args, err := %sUpdateFieldsQuery(v, cols)
+	if err != nil {
+		return err
+	}
+	res, err := db.Exec{{.Context}}({{.CtxArg}}query, args...)
+	if err != nil {
+		return err
+	}
+	if err := %sCheckVersion(res); err != nil {
+		return err
+	}
+	v.%s++
+	return nil
+}
+`
+
+// template translating the errors of the generated
+// functions of a table and wrapping them with the table and
+// function name, shared by the legacy and context apis.
+const sWrapError = `
+// %sWrapError translates the error of the %s function fn
+// and wraps it with the table and function name.
+func %sWrapError(err *error, fn string) {
+	db.WrapError(err, %q, fn, %s)
+}
+`
+
+// template checking the result of a versioned update,
+// shared by the legacy and context apis.
+const sCheckVersion = `
+// %sCheckVersion returns an ErrStaleObject if a versioned
+// update of the %s table changed no rows.
+func %sCheckVersion(res sql.Result) error {
+	return db.CheckVersion(res, %q)
+}
+`
+
+// template of the statement and parameters updating the
+// listed columns of a row, shared by the legacy and context
+// apis.
+const sUpdateFieldsQuery = `
+// %sUpdateFieldsQuery returns the statement and parameters
+// updating the columns of the row v, found by its primary key.
+func %sUpdateFieldsQuery(v *%s, cols []%sColumn) (string, []interface{}, error) {
+	a := slice%s(v)
+	var names []string
+	var args []interface{}
+	for _, col := range cols {
+		if col < 0 || int(col) >= len(%sColumns) {
+			return "", nil, fmt.Errorf("invalid %s column %%d", col)
+		}
+%s		names = append(names, %sColumns[col])
+		args = append(args, a[col])
+	}
+%s	args = append(args, %s)
+	return db.UpdateStmt(%q, names, []string{%s}, %s), args, nil
+}
+`
+
+// template setting the automatic timestamps of a row,
+// shared by the legacy and context apis.
+const sStampTime = `
+// %sStamp%s sets the automatic timestamps of v before it
+// is %s.
+func %sStamp%s(v *%s) {
+	now := db.Now()
+%s}
+`
+
+// function template to insert a row, or update the row
+// it conflicts with on a primary key or unique index.
+const sUpsert = `
+func Upsert%s%s{{.Ctx}}({{.CtxParam}}db {{.DB}}, v *%s) (err error) {
+%s%s	a := slice%s(v)
+%s%s	return nil
+}
+`
+
+// statement executing an upsert.
+const sUpsertExec = `	if _, err := db.Exec{{.Context}}({{.CtxArg}}%s, %s); err != nil {
+		return err
+	}
+`
+
+// statement reading the version of an upserted row back,
+// either with the upsert itself when it has a RETURNING
+// clause or with a select by the conflicting key after it.
+const sUpsertVersion = `	if err := db.QueryRow{{.Context}}({{.CtxArg}}%s, %s).Scan(&v.%s); err != nil {
+		return err
+	}
+`
+
 const sGetBy = `
-func Get%s%s(db db.SimpleDB, %s) (*%s, error) {
-	args := []interface{}{%s}
-	v, err :=  genericSelect%s(db, %s, args...)
+func Get%s%s{{.Ctx}}({{.CtxParam}}db {{.DB}}, %s) (_ *%s, err error) {
+%s	args := []interface{}{%s}
+	v, err :=  genericSelect%s{{.Ctx}}({{.CtxArg}}db, %s, args...)
 	return v, err
 }
 `
 
 const sFindByIndex = `
-func Find%ss%s(db db.SimpleDB, %s) ([]*%s, error) {
-	args := []interface{}{%s}
-	v, err :=  genericSelect%ss(db, %s, args...)
+func Find%ss%s{{.Ctx}}({{.CtxParam}}db {{.DB}}, %s) (_ []*%s, err error) {
+%s	args := []interface{}{%s}
+	v, err :=  genericSelect%ss{{.Ctx}}({{.CtxArg}}db, %s, args...)
 	return v, err
 }
 `
 
 const sFindByIndexInRange = `
-func Find%ss%sInRange(db db.SimpleDB, %s, limit int64, offset int64) ([]*%s, error) {
-	args := []interface{}{%s, limit, offset}
-	v, err :=  genericSelect%ss(db, %s, args...)
+func Find%ss%sInRange{{.Ctx}}({{.CtxParam}}db {{.DB}}, %s, limit int64, offset int64) (_ []*%s, err error) {
+%s	args := []interface{}{%s, limit, offset}
+	v, err :=  genericSelect%ss{{.Ctx}}({{.CtxArg}}db, %s, args...)
 	return v, err
 }
 `
 
 const sFindByForeignKey = `
-func Find%ssOf%s%s(db db.SimpleDB, %s) ([]*%s, error) {
-	args := []interface{}{%s}
-	v, err :=  genericSelect%ss(db, %s, args...)
+func Find%ssOf%s%s{{.Ctx}}({{.CtxParam}}db {{.DB}}, %s) (_ []*%s, err error) {
+%s	args := []interface{}{%s}
+	v, err :=  genericSelect%ss{{.Ctx}}({{.CtxArg}}db, %s, args...)
 	return v, err
 }
 `
 
 const sFindByForeignKeyInRange = `
-func Find%ssOf%s%sInRange(db db.SimpleDB, %s, limit int64, offset int64) ([]*%s, error) {
-	args := []interface{}{%s, limit, offset}
-	v, err :=  genericSelect%ss(db, %s, args...)
+func Find%ssOf%s%sInRange{{.Ctx}}({{.CtxParam}}db {{.DB}}, %s, limit int64, offset int64) (_ []*%s, err error) {
+%s	args := []interface{}{%s, limit, offset}
+	v, err :=  genericSelect%ss{{.Ctx}}({{.CtxArg}}db, %s, args...)
 	return v, err
 }
 `
 
 const sGetByForeignKey = `
-func Get%sOf%s%s(db db.SimpleDB, %s) (*%s, error) {
-	args := []interface{}{%s}
-	v, err :=  genericSelect%s(db, %s, args...)
+func Get%sOf%s%s{{.Ctx}}({{.CtxParam}}db {{.DB}}, %s) (_ *%s, err error) {
+%s	args := []interface{}{%s}
+	v, err :=  genericSelect%s{{.Ctx}}({{.CtxArg}}db, %s, args...)
 	return v, err
 }
 `
 
 const sFindAll = `
-func FindAll%ss(db db.SimpleDB) ([]*%s, error) {
-	args := []interface{}{}
-	v, err :=  genericSelect%ss(db, %s, args...)
+func FindAll%ss{{.Ctx}}({{.CtxParam}}db {{.DB}}) (_ []*%s, err error) {
+%s	args := []interface{}{}
+	v, err :=  genericSelect%ss{{.Ctx}}({{.CtxArg}}db, %s, args...)
 	return v, err
 }
 `
 
+// function template to select all rows of a table with
+// a soft delete field, including the rows marked as deleted.
+const sFindAllWithDeleted = `
+func FindAll%ssWithDeleted{{.Ctx}}({{.CtxParam}}db {{.DB}}) (_ []*%s, err error) {
+%s	return genericSelect%ss{{.Ctx}}({{.CtxArg}}db, %s)
+}
+`
+
 const sFindAllInRange = `
-func FindAll%ssInRange(db db.SimpleDB, limit int64, offset int64) ([]*%s, error) {
-	args := []interface{}{limit, offset}
-	v, err :=  genericSelect%ss(db, %s, args...)
+func FindAll%ssInRange{{.Ctx}}({{.CtxParam}}db {{.DB}}, limit int64, offset int64) (_ []*%s, err error) {
+%s	args := []interface{}{limit, offset}
+	v, err :=  genericSelect%ss{{.Ctx}}({{.CtxArg}}db, %s, args...)
 	return v, err
 }
 `
 
+// function template to select the page of rows following
+// the cursor, ordered by the primary key. An empty cursor
+// selects the first page.
+const sFindAfter = `
+func Find%ss%sAfter{{.Ctx}}({{.CtxParam}}db {{.DB}}, %scursor string, limit int64) (_ []*%s, _ string, err error) {
+%s	args := []interface{}{%s}
+	query := %s
+	if cursor != "" {
+		keys, err := %sCursorArgs(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		args = append(args, keys...)
+		query = %s
+	}
+	args = append(args, limit)
+	vv, err := genericSelect%ss{{.Ctx}}({{.CtxArg}}db, query, args...)
+	if err != nil || len(vv) == 0 || int64(len(vv)) < limit {
+		return vv, "", err
+	}
+	next, err := %sCursor(vv[len(vv)-1])
+	return vv, next, err
+}
+`
+
+// function templates to encode and decode the primary
+// key of a row as an opaque cursor.
+const sCursor = `
+func %sCursor(v *%s) (string, error) {
+	return db.EncodeCursor(%s)
+}
+
+func %sCursorArgs(cursor string) ([]interface{}, error) {
+%s	if err := db.DecodeCursor(cursor, %s); err != nil {
+		return nil, err
+	}
+	return []interface{}{%s}, nil
+}
+`
+
+// template of the enum of the columns of a table, which
+// is the whitelist of columns the sorted finders accept.
+const sColumns = `
+// %sColumn is a column of the %s table, used to sort
+// the results of the generated finders.
+type %sColumn int
+
+const (
+%s)
+
+var %sColumns = [...]string{%s}
+
+// %sOrderBy returns the sort column and direction of an
+// ORDER BY clause, followed by the primary key to break ties.
+// Only the names of the table's columns are ever returned, so
+// the clause is safe to format into SQL.
+func %sOrderBy(by %sColumn, dir db.Direction) (string, error) {
+	if by < 0 || int(by) >= len(%sColumns) {
+		return "", fmt.Errorf("invalid %s column %%d", by)
+	}
+	return %sColumns[by] + " " + dir.String()%s, nil
+}
+`
+
+// function template to select rows sorted by a column.
+const sFindSorted = `
+func Find%s%sSorted{{.Ctx}}({{.CtxParam}}db {{.DB}}, %sby %sColumn, dir db.Direction%s) (_ []*%s, err error) {
+%s	order, err := %sOrderBy(by, dir)
+	if err != nil {
+		return nil, err
+	}
+	args := []interface{}{%s}
+	return genericSelect%ss{{.Ctx}}({{.CtxArg}}db, fmt.Sprintf(%s, order), args...)
+}
+`
+
+// template of the query builder of a table, which
+// adds conditions and sorting on its columns at runtime.
+const sQuery = `
+// %sQueryBuilder builds a query of the %s table from
+// conditions on its columns. The column names are fixed by
+// the generated methods and the values are always passed as
+// parameters.
+type %sQueryBuilder struct {
+	q *db.Query
+}
+
+// %sQuery returns a query of all rows of the %s table, in
+// the table's default order until sorted with an OrderBy
+// method.
+func %sQuery() *%sQueryBuilder {
+	return &%sQueryBuilder{db.NewQuery(%s, %s, %s%s)%s}
+}
+
+// Limit limits the number of rows returned.
+func (b *%sQueryBuilder) Limit(n int64) *%sQueryBuilder {
+	b.q.Limit(n)
+	return b
+}
+
+// Offset skips the first n rows. It requires a limit.
+func (b *%sQueryBuilder) Offset(n int64) *%sQueryBuilder {
+	b.q.Offset(n)
+	return b
+}
+
+// SQL returns the statement and parameters of the query,
+// or the first error in building it.
+func (b *%sQueryBuilder) SQL() (string, []interface{}, error) {
+	return b.q.SQL()
+}
+`
+
+// template of the query of a table with a soft delete
+// field that includes the rows marked as deleted.
+const sQueryWithDeleted = `
+// %sQueryWithDeleted returns a query of all rows of the %s
+// table, including the rows marked as deleted.
+func %sQueryWithDeleted() *%sQueryBuilder {
+	return &%sQueryBuilder{db.NewQuery(%s, %s, %s%s)}
+}
+`
+
+// template of the methods of a query builder adding a
+// condition on a column and sorting by it.
+const sQueryColumn = `
+// Where%s adds a condition on the %s column.
+func (b *%sQueryBuilder) Where%s(op db.Op, values ...%s) *%sQueryBuilder {
+	args := make([]interface{}, len(values))
+	for i, v := range values {
+		args[i] = v
+	}
+	b.q.Where(%q, op, args...)
+	return b
+}
+
+// OrderBy%s sorts the rows by the %s column.
+func (b *%sQueryBuilder) OrderBy%s(dir db.Direction) *%sQueryBuilder {
+	b.q.OrderBy(%q, dir)
+	return b
+}
+`
+
+// function template to select the rows of a query.
+const sQueryAll = `
+func (b *%sQueryBuilder) All{{.Ctx}}({{.CtxParam}}db {{.DB}}) (_ []*%s, err error) {
+%s	query, args, err := b.q.SQL()
+	if err != nil {
+		return nil, err
+	}
+	return genericSelect%ss{{.Ctx}}({{.CtxArg}}db, query, args...)
+}
+`
+
 const sCount = `
-func Count%s(db db.SimpleDB)(int, error){
-    var count int
-	row := db.QueryRow(%s)
-	err := row.Scan(&count)
+func Count%s{{.Ctx}}({{.CtxParam}}db {{.DB}})(_ int, err error){
+%s    var count int
+	row := db.QueryRow{{.Context}}({{.CtxArg}}%s)
+	err = row.Scan(&count)
 	return count, err
 }
 `
 
 const sCountByIndex = `
-func Count%s%s(db db.SimpleDB, %s)(int, error){
-    var count int
+func Count%s%s{{.Ctx}}({{.CtxParam}}db {{.DB}}, %s)(_ int, err error){
+%s    var count int
     args := []interface{}{%s}
-	row := db.QueryRow(%s, args...)
-	err := row.Scan(&count)
+	row := db.QueryRow{{.Context}}({{.CtxArg}}%s, args...)
+	err = row.Scan(&count)
 	return count, err
 }
 `
+
+// template of the store interface of a table and its
+// implementation calling the generated functions.
+const sStore = `
+// %sStore lists the generated %s functions, so that
+// callers may depend on the interface and use a fake in tests.
+type %sStore interface {
+%s}
+
+type sql%sStore struct {
+	db %s
+}
+
+// New%sStore returns a %sStore calling the generated
+// functions with db.
+func New%sStore(db %s) %sStore {
+	return &sql%sStore{db}
+}
+%s`
+
+// template of the helpers of the in-memory store of a
+// table. The {{.X}} markers are replaced by writeStore.
+const sFakeStore = `
+// fake{{.Type}}Store is an in-memory {{.Type}}Store for tests. It
+// stores copies of the values, keeps the primary key and
+// unique indexes unique, and is not safe for concurrent use.
+// The copies have their own pointers, slices and maps for the
+// columns, but share their elements, and the fields that are
+// not stored, with the values.
+type fake{{.Type}}Store struct {
+	rows []*{{.Qualified}}
+	next int64
+}
+
+// NewFake{{.Type}}Store returns an empty in-memory {{.Type}}Store.
+func NewFake{{.Type}}Store() {{.Type}}Store {
+	return &fake{{.Type}}Store{}
+}
+
+// find returns copies of the rows matching the function,
+// in the table's default order, as they are scanned.
+func (f *fake{{.Type}}Store) find(match func(*{{.Qualified}}) bool) ([]*{{.Qualified}}, error) {
+	var vv []*{{.Qualified}}
+	for _, r := range f.rows {
+		if match(r) {
+			vv = append(vv, clone{{.Type}}(r))
+		}
+	}
+{{.Order}}{{.AfterScan}}	return vv, nil
+}
+
+// get returns a copy of the first row matching the
+// function, or db.ErrNotFound.
+func (f *fake{{.Type}}Store) get(match func(*{{.Qualified}}) bool) (*{{.Qualified}}, error) {
+	vv, err := f.find(match)
+	if err != nil {
+		return nil, err
+	}
+	if len(vv) == 0 {
+		return nil, db.ErrNotFound
+	}
+	return vv[0], nil
+}
+
+// count returns the number of rows matching the function.
+func (f *fake{{.Type}}Store) count(match func(*{{.Qualified}}) bool) int {
+	var n int
+	for _, r := range f.rows {
+		if match(r) {
+			n++
+		}
+	}
+	return n
+}
+
+// index returns the position of the first row matching
+// the function, or -1.
+func (f *fake{{.Type}}Store) index(match func(*{{.Qualified}}) bool) int {
+	for i, r := range f.rows {
+		if match(r) {
+			return i
+		}
+	}
+	return -1
+}
+
+// delete removes the rows matching the function.
+func (f *fake{{.Type}}Store) delete(match func(*{{.Qualified}}) bool) {
+	var rows []*{{.Qualified}}
+	for _, r := range f.rows {
+		if !match(r) {
+			rows = append(rows, r)
+		}
+	}
+	f.rows = rows
+}
+
+// conflict returns an error if v has the same primary key
+// or unique index as any row other than the one at skip.
+func (f *fake{{.Type}}Store) conflict(v *{{.Qualified}}, skip int) error {
+{{.Conflict}}}
+
+// insert stores a copy of v, first assigning the next
+// generated key unless keep is true.
+func (f *fake{{.Type}}Store) insert(v *{{.Qualified}}, keep bool) error {
+{{.NextId}}	if err := f.conflict(v, -1); err != nil {
+		return err
+	}
+	f.rows = append(f.rows, clone{{.Type}}(v))
+	return nil
+}
+
+// update replaces the row at i with a copy of v.
+func (f *fake{{.Type}}Store) update(i int, v *{{.Qualified}}) error {
+	if err := f.conflict(v, i); err != nil {
+		return err
+	}
+	f.rows[i] = clone{{.Type}}(v)
+	return nil
+}
+
+// clone{{.Type}} returns a copy of v that shares no memory with
+// v through its columns.
+func clone{{.Type}}(v *{{.Qualified}}) *{{.Qualified}} {
+	c := *v
+{{.Clone}}	return &c
+}
+
+{{.After}}
+// sorted returns the rows matching the function sorted
+// by the column and direction, then by the primary key.
+func (f *fake{{.Type}}Store) sorted(match func(*{{.Qualified}}) bool, by {{.Type}}Column, dir db.Direction) ([]*{{.Qualified}}, error) {
+	if _, err := {{.Name}}OrderBy(by, dir); err != nil {
+		return nil, err
+	}
+	vv, err := f.find(match)
+	if err != nil {
+		return nil, err
+	}
+{{.SortKey}}	db.SortByColumn(vv, func(i int) interface{} {
+		return {{.Name}}ColumnValue(vv[i], by)
+	}, dir == db.Desc, {{.Dialect}})
+	return vv, nil
+}
+
+func {{.Name}}ColumnValue(v *{{.Qualified}}, by {{.Type}}Column) interface{} {
+	switch by {
+{{.ColumnValues}}	}
+	return nil
+}
+
+func page{{.Type}}s(vv []*{{.Qualified}}, limit, offset int64) []*{{.Qualified}} {
+	if offset >= int64(len(vv)) {
+		return nil
+	}
+	vv = vv[offset:]
+	if limit < int64(len(vv)) {
+		vv = vv[:limit]
+	}
+	return vv
+}
+`
+
+// template of the helpers paging through the rows of the
+// in-memory store by the primary key.
+const sFakeStoreAfter = `
+// after returns the page of rows matching the function
+// that follows the cursor, ordered by the primary key.
+func (f *fake{{.Type}}Store) after(match func(*{{.Qualified}}) bool, cursor string, limit int64) ([]*{{.Qualified}}, string, error) {
+	vv, err := f.find(match)
+	if err != nil {
+		return nil, "", err
+	}
+	db.SortByKey(vv, func(i int) []interface{} {
+		return {{.Name}}Key(vv[i])
+	})
+	if cursor != "" {
+		keys, err := {{.Name}}CursorArgs(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		for len(vv) != 0 && !db.Less(keys, {{.Name}}Key(vv[0])) {
+			vv = vv[1:]
+		}
+	}
+	vv = page{{.Type}}s(vv, limit, 0)
+	if len(vv) == 0 || int64(len(vv)) < limit {
+		return vv, "", nil
+	}
+	next, err := {{.Name}}Cursor(vv[len(vv)-1])
+	return vv, next, err
+}
+
+func {{.Name}}Key(v *{{.Qualified}}) []interface{} {
+	return []interface{}{ {{.Keys}} }
+}
+`